@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
 )
 
 // The ABI holds information about a contract's context and available
@@ -108,8 +109,22 @@ func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Pack up the method ID too if not a constructor and return
-	return append(method.ID, arguments...), nil
+	// Pack up the method ID too if not a constructor and return. The ID is
+	// copied, appending to it would write into the array behind it, which
+	// every call shares.
+	ret := make([]byte, 0, len(method.ID)+len(arguments))
+	ret = append(ret, method.ID...)
+	return append(ret, arguments...), nil
+}
+
+// PackCall is Pack for method calls. Pack takes an empty name as the
+// constructor and returns its packed arguments without a selector, PackCall
+// rejects it instead.
+func (abi ABI) PackCall(name string, args ...interface{}) ([]byte, error) {
+	if name == "" {
+		return nil, fmt.Errorf("abi: no method name given")
+	}
+	return abi.Pack(name, args...)
 }
 
 // Unpack output in v according to the abi specification
@@ -175,6 +190,12 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 	abi.Methods = make(map[string]Method)
 	abi.Events = make(map[string]Event)
 	for _, field := range fields {
+		if err := Arguments(field.Inputs).checkNames(); err != nil {
+			return fmt.Errorf("abi: inputs of %s %q: %v", field.Type, field.Name, err)
+		}
+		if err := Arguments(field.Outputs).checkNames(); err != nil {
+			return fmt.Errorf("abi: outputs of %s %q: %v", field.Type, field.Name, err)
+		}
 		switch field.Type {
 		case "constructor":
 			abi.Constructor = NewMethod("", "", Constructor, field.StateMutability, field.Constant, field.Payable, field.Inputs, nil)
@@ -274,4 +295,31 @@ func (abi *ABI) HasReceive() bool {
 }
 
 // revertSelector is a special function selector for revert reason unpacking.
-//var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+var (
+	// ErrNoRevertReason is returned by UnpackRevert for a revert without data.
+	ErrNoRevertReason = errors.New("abi: revert without reason")
+	// ErrNotRevertReason is returned by UnpackRevert for data that does not
+	// start with the Error(string) selector.
+	ErrNotRevertReason = errors.New("abi: data is not a revert reason")
+)
+
+// UnpackRevert resolves the abi-encoded revert reason. According to the solidity
+// spec https://solidity.readthedocs.io/en/latest/control-structures.html#revert,
+// the provided revert reason is abi-encoded as if it were a call to a function
+// `Error(string)`. So it's a special tool for it.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrNoRevertReason
+	}
+	if len(data) < 4 || !bytes.Equal(data[:4], revertSelector) {
+		return "", ErrNotRevertReason
+	}
+	typ, _ := NewType("string", "", nil)
+	var reason string
+	if err := (Arguments{{Type: typ}}).Unpack(&reason, data[4:]); err != nil {
+		return "", err
+	}
+	return reason, nil
+}
@@ -17,23 +17,41 @@
 package abi
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"reflect"
 	"strings"
 
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/common/math"
+	"github.com/sero-cash/go-sero/crypto"
 
 	"github.com/sero-cash/go-czero-import/c_type"
 )
 
 // Argument holds the name of the argument and the corresponding type.
 // Types are used when packing and testing arguments.
+//
+// A parsed Argument is never modified by the pack and unpack paths, all type
+// information is computed up front by NewType. It is therefore safe to share a
+// parsed Argument (and the Type pointers it holds) between goroutines.
 type Argument struct {
 	Name    string
 	Type    Type
 	Indexed bool // indexed is only used by events
+
+	// Encoder optionally replaces the built-in packing of this argument's value.
+	// The encoder is responsible for the complete encoding of the value: for a
+	// static type the result is written in place as the argument's head and must
+	// be exactly as long as the head (32 bytes, or 32 bytes per element for
+	// static arrays). For a dynamic type (string, bytes, slices) the result is
+	// appended to the tail, including any length prefix, and Pack writes the
+	// offset into the head as usual.
+	Encoder func(interface{}) ([]byte, error)
 }
 
 type Arguments []Argument
@@ -64,6 +82,23 @@ func (argument *Argument) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// checkNames returns an error if two arguments share a non empty name, which
+// would make one of them shadow the other when unpacking into a map or a
+// struct. Unnamed arguments may repeat.
+func (arguments Arguments) checkNames() error {
+	seen := make(map[string]bool)
+	for _, arg := range arguments {
+		if arg.Name == "" {
+			continue
+		}
+		if seen[arg.Name] {
+			return fmt.Errorf("duplicate argument name %q", arg.Name)
+		}
+		seen[arg.Name] = true
+	}
+	return nil
+}
+
 // LengthNonIndexed returns the number of arguments when not counting 'indexed' ones. Only events
 // can ever have 'indexed' arguments, it should always be false on arguments for method input/output
 func (arguments Arguments) LengthNonIndexed() int {
@@ -76,6 +111,53 @@ func (arguments Arguments) LengthNonIndexed() int {
 	return out
 }
 
+// ExpectedTopics returns the number of topics a log of an event taking these
+// arguments carries: one per indexed argument, plus the event signature hash
+// as the first topic unless the event is anonymous. Anonymous events leave
+// the signature out, so all of their topics are indexed arguments.
+func (arguments Arguments) ExpectedTopics(anonymous bool) int {
+	topics := len(arguments) - arguments.LengthNonIndexed()
+	if !anonymous {
+		topics++
+	}
+	return topics
+}
+
+// PackTopics packs the values of the indexed arguments, in order, into the
+// topics a log carries for them, e.g. to build a log filter. Value types such
+// as integers and addresses take their packed word as the topic. Strings,
+// bytes, arrays and tuples take the keccak256 hash of their in-place encoding
+// instead, as the ABI spec defines for indexed arguments.
+func (arguments Arguments) PackTopics(args ...interface{}) ([]common.Hash, error) {
+	var indexed Arguments
+	for _, arg := range arguments {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(args) != len(indexed) {
+		return nil, fmt.Errorf("argument count mismatch: %d for %d indexed", len(args), len(indexed))
+	}
+	topics := make([]common.Hash, len(args))
+	for i, arg := range indexed {
+		switch arg.Type.T {
+		case StringTy, BytesTy, SliceTy, ArrayTy, TupleTy:
+			enc, err := arg.Type.packInPlace(reflect.ValueOf(args[i]), true)
+			if err != nil {
+				return nil, fmt.Errorf("abi: topic of %s: %v", arg.Name, err)
+			}
+			topics[i] = crypto.Keccak256Hash(enc)
+		default:
+			packed, err := arg.pack(args[i])
+			if err != nil {
+				return nil, fmt.Errorf("abi: topic of %s: %v", arg.Name, err)
+			}
+			topics[i] = common.BytesToHash(packed)
+		}
+	}
+	return topics, nil
+}
+
 // NonIndexed returns the arguments with indexed arguments filtered out
 func (arguments Arguments) NonIndexed() Arguments {
 	var ret []Argument
@@ -87,6 +169,56 @@ func (arguments Arguments) NonIndexed() Arguments {
 	return ret
 }
 
+// Copy returns a copy of the arguments that can be modified without touching
+// the receiver. Names and Indexed flags are copied, the parsed types and the
+// Encoders are shared, as neither is ever modified once set up.
+func (arguments Arguments) Copy() Arguments {
+	if arguments == nil {
+		return nil
+	}
+	ret := make(Arguments, len(arguments))
+	copy(ret, arguments)
+	return ret
+}
+
+// AsNonIndexed returns a copy of the arguments with every Indexed flag
+// cleared, so event arguments can be packed as a plain method-style tuple.
+// The receiver is left untouched; the types are shared as they are read-only.
+func (arguments Arguments) AsNonIndexed() Arguments {
+	ret := make(Arguments, len(arguments))
+	copy(ret, arguments)
+	for i := range ret {
+		ret[i].Indexed = false
+	}
+	return ret
+}
+
+// SameSelector reports whether a method called name taking these arguments
+// has the same 4 byte selector as a method called otherName taking other.
+// Selectors only depend on the method name and the argument types, so
+// renaming arguments keeps them while changing any type breaks them.
+func (arguments Arguments) SameSelector(name string, other Arguments, otherName string) bool {
+	return bytes.Equal(arguments.selector(name), other.selector(otherName))
+}
+
+// CanonicalTypes returns the canonical parenthesized list of the argument
+// types, e.g. (uint256,address[],(string,bytes)[2]), as hashed into method
+// selectors and event signatures. Aliases such as uint are spelled out and no
+// spaces or names are included, matching Solidity.
+func (arguments Arguments) CanonicalTypes() string {
+	types := make([]string, len(arguments))
+	for i, arg := range arguments {
+		types[i] = arg.Type.String()
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+// selector returns the 4 byte selector of a method called name taking the
+// arguments.
+func (arguments Arguments) selector(name string) []byte {
+	return crypto.Keccak256([]byte(name + arguments.CanonicalTypes()))[:4]
+}
+
 // isTuple returns true for non-atomic constructs, like (uint,uint) or uint[]
 func (arguments Arguments) isTuple() bool {
 	return len(arguments) > 1
@@ -107,6 +239,9 @@ func (arguments Arguments) Unpack(v interface{}, data []byte) error {
 	if len(marshalledValues) == 0 {
 		return fmt.Errorf("abi: Unpack(no-values unmarshalled %T)", v)
 	}
+	if settable, ok := v.(ABISettable); ok {
+		return arguments.NonIndexed().unpackIntoSetter(settable, marshalledValues)
+	}
 
 	if arguments.isTuple() {
 		return arguments.unpackTuple(v, marshalledValues)
@@ -114,6 +249,23 @@ func (arguments Arguments) Unpack(v interface{}, data []byte) error {
 	return arguments.unpackAtomic(v, marshalledValues[0])
 }
 
+// ABISettable is implemented by types, such as generated ones, that take the
+// decoded values through a setter rather than exported fields. Unpack hands
+// such a destination every non-indexed argument by name.
+type ABISettable interface {
+	SetABIField(name string, v interface{}) error
+}
+
+// unpackIntoSetter passes marshalledValues to the setter, one call per argument.
+func (arguments Arguments) unpackIntoSetter(settable ABISettable, marshalledValues []interface{}) error {
+	for i, arg := range arguments {
+		if err := settable.SetABIField(arg.Name, marshalledValues[i]); err != nil {
+			return fmt.Errorf("abi: set field %s: %v", arg.Name, err)
+		}
+	}
+	return nil
+}
+
 // UnpackIntoMap performs the operation hexdata -> mapping of argument name to argument value
 func (arguments Arguments) UnpackIntoMap(v map[string]interface{}, data []byte) error {
 	marshalledValues, err := arguments.UnpackValues(data)
@@ -156,7 +308,7 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 		for i, arg := range nonIndexedArgs {
 			field := value.FieldByName(abi2struct[arg.Name])
 			if !field.IsValid() {
-				return fmt.Errorf("abi: field %s can't be found in the given value", arg.Name)
+				return unpackErrorf(ErrFieldNotFound, "abi: field %s can't be found in the given value", arg.Name)
 			}
 			if err := set(field, reflect.ValueOf(marshalledValues[i])); err != nil {
 				return err
@@ -164,7 +316,7 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 		}
 	case reflect.Slice, reflect.Array:
 		if value.Len() < len(marshalledValues) {
-			return fmt.Errorf("abi: insufficient number of arguments for unpack, want %d, got %d", len(arguments), value.Len())
+			return unpackErrorf(ErrTupleMismatch, "abi: insufficient number of arguments for unpack, want %d, got %d", len(arguments), value.Len())
 		}
 		for i := range nonIndexedArgs {
 			if err := set(value.Index(i), reflect.ValueOf(marshalledValues[i])); err != nil {
@@ -172,7 +324,7 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 			}
 		}
 	default:
-		return fmt.Errorf("abi:[2] cannot unmarshal tuple in to %v", value.Type())
+		return unpackErrorf(ErrTupleMismatch, "abi:[2] cannot unmarshal tuple in to %v", value.Type())
 	}
 	return nil
 }
@@ -190,7 +342,12 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 
 // Computes the full size of an array;
 // i.e. counting nested arrays, which count towards size for unpacking.
+// Static arrays are measured in words, so that multi-word elements such as
+// static tuples count with their full encoded size.
 func getArraySize(arr *Type) int {
+	if !isDynamicType(*arr) {
+		return getTypeSize(*arr) / 32
+	}
 	size := arr.Size
 	// Arrays can be nested, with each element being the same size
 	arr = arr.Elem
@@ -207,11 +364,126 @@ func getArraySize(arr *Type) int {
 // without supplying a struct to unpack into. Instead, this method returns a list containing the
 // values. An atomic argument will be a list with one element.
 func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
-	retval := make([]interface{}, 0, arguments.LengthNonIndexed())
+	nonIndexed := arguments.NonIndexed()
+	return unpackValues(nonIndexed, nonIndexed.headOffsets(), data)
+}
+
+// UnpackValuesAt is like UnpackValues for an encoding embedded in data that
+// starts at base, with all of its offsets relative to base. Re-slicing a Go
+// slice shares its memory, so this decodes the view from base without
+// copying the surrounding buffer.
+func (arguments Arguments) UnpackValuesAt(data []byte, base int) ([]interface{}, error) {
+	if base < 0 || base > len(data) {
+		return nil, fmt.Errorf("abi: base %d out of range for %d bytes", base, len(data))
+	}
+	return arguments.UnpackValues(data[base:])
+}
+
+// EstimateAlloc returns an upper bound on the bytes UnpackValues would
+// allocate for the decoded values of data, walking the declared offsets and
+// length prefixes with the same bounds checks but without allocating them.
+// It can serve as an admission check before decoding untrusted data.
+func (arguments Arguments) EstimateAlloc(data []byte) (int, error) {
+	nonIndexed := arguments.NonIndexed()
+	offsets := nonIndexed.headOffsets()
+	total := 0
+	for index, arg := range nonIndexed {
+		size, err := estimateAlloc(offsets[index], arg.Type, data)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// ArgByteRange is where a top level argument lies in packed data, as
+// [start,end) byte ranges. The head is the argument's slot, or slots for a
+// static array or tuple. Dynamic arguments also have a tail, from where their
+// head points to up to the end of their encoding, padding included.
+type ArgByteRange struct {
+	Name      string
+	HeadStart int
+	HeadEnd   int
+	Dynamic   bool
+	TailStart int
+	TailEnd   int
+}
+
+// ByteRanges maps out the head and, for dynamic arguments, the tail of every
+// non indexed argument in data, e.g. to highlight them in a hex viewer. The
+// offsets and lengths are bounds checked and a range that runs past the end
+// of data is reported as truncated.
+func (arguments Arguments) ByteRanges(data []byte) ([]ArgByteRange, error) {
+	nonIndexed := arguments.NonIndexed()
+	offsets := nonIndexed.headOffsets()
+	ranges := make([]ArgByteRange, len(nonIndexed))
+	for index, arg := range nonIndexed {
+		r := ArgByteRange{Name: arg.Name, HeadStart: offsets[index], HeadEnd: offsets[index] + getTypeSize(arg.Type)}
+		if r.HeadEnd > len(data) {
+			return nil, unpackErrorf(ErrInsufficientData, "abi: head of argument %d (%s) truncated: need %d bytes, have %d", index, arg.Name, r.HeadEnd, len(data))
+		}
+		if isDynamicType(arg.Type) {
+			start, err := readOffset(data, r.HeadStart, 0)
+			if err != nil {
+				return nil, prefixUnpackError(err, "abi: argument %d (%s): ", index, arg.Name)
+			}
+			end, err := encodingEnd(arg.Type, data, start)
+			if err != nil {
+				return nil, prefixUnpackError(err, "abi: argument %d (%s): ", index, arg.Name)
+			}
+			r.Dynamic, r.TailStart, r.TailEnd = true, start, end
+		}
+		ranges[index] = r
+	}
+	return ranges, nil
+}
+
+// UnpackToChan is like UnpackValues, but sends every decoded value to out in
+// argument order as soon as it is decoded. The channel is owned by the caller
+// and is never closed; on a decode error the values of the preceding
+// arguments have already been sent and the error is returned.
+func (arguments Arguments) UnpackToChan(data []byte, out chan<- interface{}) error {
+	nonIndexed := arguments.NonIndexed()
+	offsets := nonIndexed.headOffsets()
+	for index, arg := range nonIndexed {
+		value, err := toGoType(offsets[index], arg.Type, data)
+		if err != nil {
+			return err
+		}
+		out <- value
+	}
+	return nil
+}
+
+// UnpackValuesAll is like UnpackValues, but decodes every argument
+// independently instead of stopping at the first failure. Arguments that fail
+// to decode are left nil in the values and the returned errors are aligned
+// with the arguments, nil where decoding succeeded. Note that a corrupted head
+// still reads at the position the arguments declare, so a broken offset or
+// length of one dynamic field can not be recovered from.
+func (arguments Arguments) UnpackValuesAll(data []byte) ([]interface{}, []error) {
+	nonIndexed := arguments.NonIndexed()
+	offsets := nonIndexed.headOffsets()
+	values := make([]interface{}, len(nonIndexed))
+	errs := make([]error, len(nonIndexed))
+	for index, arg := range nonIndexed {
+		values[index], errs[index] = toGoType(offsets[index], arg.Type, data)
+		if errs[index] != nil {
+			values[index] = nil
+		}
+	}
+	return values, errs
+}
+
+// headOffsets returns the offset of the head of each argument within the
+// encoded data.
+func (arguments Arguments) headOffsets() []int {
+	offsets := make([]int, len(arguments))
 	virtualArgs := 0
-	for index, arg := range arguments.NonIndexed() {
-		marshalledValue, err := toGoType((index+virtualArgs)*32, arg.Type, data)
-		if arg.Type.T == ArrayTy {
+	for index, arg := range arguments {
+		offsets[index] = (index + virtualArgs) * 32
+		if arg.Type.T == ArrayTy && !isDynamicType(arg.Type) {
 			// If we have a static array, like [3]uint256, these are coded as
 			// just like uint256,uint256,uint256.
 			// This means that we need to add two 'virtual' arguments when
@@ -223,7 +495,20 @@ func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
 			// Calculate the full array size to get the correct offset for the next argument.
 			// Decrement it by 1, as the normal index increment is still applied.
 			virtualArgs += getArraySize(&arg.Type) - 1
+		} else if arg.Type.T == TupleTy && !isDynamicType(arg.Type) {
+			// Static tuples are encoded inline as well.
+			virtualArgs += getTypeSize(arg.Type)/32 - 1
 		}
+	}
+	return offsets
+}
+
+// unpackValues unpacks the given arguments, reading the head of each one at
+// the matching offset.
+func unpackValues(arguments Arguments, offsets []int, data []byte) ([]interface{}, error) {
+	retval := make([]interface{}, 0, len(arguments))
+	for index, arg := range arguments {
+		marshalledValue, err := toGoType(offsets[index], arg.Type, data)
 		if err != nil {
 			return nil, err
 		}
@@ -232,6 +517,34 @@ func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
 	return retval, nil
 }
 
+// UnpackMulticall decodes the output of a multicall contract. The arguments
+// are expected to describe a single bytes[] output, each element of which is
+// the ABI encoded return data of a sub call and is decoded with the matching
+// entry of perCall.
+func (arguments Arguments) UnpackMulticall(data []byte, perCall []Arguments) ([][]interface{}, error) {
+	values, err := arguments.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("abi: multicall output must be a single bytes[], got %d values", len(values))
+	}
+	results, ok := values[0].([][]byte)
+	if !ok {
+		return nil, fmt.Errorf("abi: multicall output must be bytes[], got %T", values[0])
+	}
+	if len(results) != len(perCall) {
+		return nil, fmt.Errorf("abi: multicall returned %d results, expected %d", len(results), len(perCall))
+	}
+	ret := make([][]interface{}, len(results))
+	for i, result := range results {
+		if ret[i], err = perCall[i].UnpackValues(result); err != nil {
+			return nil, fmt.Errorf("abi: multicall result %d: %v", i, err)
+		}
+	}
+	return ret, nil
+}
+
 // PackValues performs the operation Go format -> Hexdata
 // It is the semantic opposite of UnpackValues
 func (arguments Arguments) PackValues(args []interface{}) ([]byte, error) {
@@ -263,8 +576,187 @@ func (arguments Arguments) PackPrefix(args ...interface{}) ([]byte, error) {
 
 }
 
+// UnpackPrefix decodes the address prefix written by PackPrefix: a 2 byte big
+// endian count followed by that many PKrs. Data whose length disagrees with
+// the count, as when it was truncated, is rejected.
+func UnpackPrefix(data []byte) ([]c_type.PKr, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("abi: address prefix too short: %d bytes", len(data))
+	}
+	var pkr c_type.PKr
+	count := int(binary.BigEndian.Uint16(data))
+	if want := 2 + count*len(pkr); len(data) != want {
+		return nil, fmt.Errorf("abi: address prefix of %d PKrs needs %d bytes, have %d", count, want, len(data))
+	}
+	pkrs := make([]c_type.PKr, count)
+	for i := range pkrs {
+		copy(pkrs[i][:], data[2+i*len(pkr):])
+	}
+	return pkrs, nil
+}
+
 // Pack performs the operation Go format -> Hexdata
 func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
+	return arguments.pack(arguments.headSize(), args)
+}
+
+// PackValidated is like Pack, but first checks every value against its
+// argument type and only packs once all of them match. Errors name the index,
+// name and type of the offending argument along with the expected and actual
+// kind. Arguments with a custom Encoder are left for the encoder to check.
+func (arguments Arguments) PackValidated(args ...interface{}) ([]byte, error) {
+	if len(args) != len(arguments) {
+		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(args), len(arguments))
+	}
+	for i, arg := range arguments {
+		if arg.Encoder != nil {
+			continue
+		}
+		if err := arg.Type.validate(reflect.ValueOf(args[i])); err != nil {
+			return nil, fmt.Errorf("abi: argument %d (%s %v): %v", i, arg.Name, arg.Type, err)
+		}
+	}
+	return arguments.Pack(args...)
+}
+
+// TailSize returns the number of bytes the dynamic values among args take up
+// after the heads when packed, length prefixes and padding included. Together
+// with the size of the heads it gives the exact length Pack produces. The
+// dynamic values are encoded to be measured, using custom encoders if set.
+func (arguments Arguments) TailSize(args ...interface{}) (int, error) {
+	if len(args) != len(arguments) {
+		return 0, fmt.Errorf("argument count mismatch: %d for %d", len(args), len(arguments))
+	}
+	size := 0
+	for i, arg := range arguments {
+		if !isDynamicType(arg.Type) {
+			continue
+		}
+		packed, err := arg.pack(args[i])
+		if err != nil {
+			return 0, err
+		}
+		size += len(packed)
+	}
+	return size, nil
+}
+
+// PackChanged compares the updated values with the baseline ones and packs
+// only the values that changed, in argument order. Values are equal when they
+// encode the same, so e.g. *big.Int values are compared numerically and byte
+// values by content. Bit i of the returned mask, read as a big endian integer
+// of (len(arguments)+7)/8 bytes, is set when argument i changed.
+func (arguments Arguments) PackChanged(baseline, updated []interface{}) (mask []byte, packed []byte, err error) {
+	if len(baseline) != len(arguments) || len(updated) != len(arguments) {
+		return nil, nil, fmt.Errorf("argument count mismatch: %d and %d for %d", len(baseline), len(updated), len(arguments))
+	}
+	mask = make([]byte, (len(arguments)+7)/8)
+	var (
+		changed Arguments
+		values  []interface{}
+	)
+	for i, arg := range arguments {
+		before, err := arg.pack(baseline[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("abi: baseline argument %d (%s): %v", i, arg.Name, err)
+		}
+		after, err := arg.pack(updated[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("abi: updated argument %d (%s): %v", i, arg.Name, err)
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+		mask[len(mask)-1-i/8] |= 1 << uint(i%8)
+		changed = append(changed, arg)
+		values = append(values, updated[i])
+	}
+	if packed, err = changed.Pack(values...); err != nil {
+		return nil, nil, err
+	}
+	return mask, packed, nil
+}
+
+// UnpackChanged decodes the mask and values produced by PackChanged, returning
+// the values of the changed arguments keyed by their index. The mask must have
+// the length PackChanged gives it, with no bits set past the last argument,
+// and packed must hold a value for every set bit.
+func (arguments Arguments) UnpackChanged(mask []byte, packed []byte) (map[int]interface{}, error) {
+	if want := (len(arguments) + 7) / 8; len(mask) != want {
+		return nil, fmt.Errorf("abi: changed mask of %d bytes, want %d", len(mask), want)
+	}
+	var (
+		changed Arguments
+		indices []int
+	)
+	for i := 0; i < len(mask)*8; i++ {
+		if mask[len(mask)-1-i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		if i >= len(arguments) {
+			return nil, fmt.Errorf("abi: changed mask sets bit %d, but there are only %d arguments", i, len(arguments))
+		}
+		changed = append(changed, arguments[i])
+		indices = append(indices, i)
+	}
+	if len(changed) == 0 {
+		if len(packed) != 0 {
+			return nil, fmt.Errorf("abi: changed mask is empty, but %d bytes are packed", len(packed))
+		}
+		return map[int]interface{}{}, nil
+	}
+	values, err := changed.UnpackValues(packed)
+	if err != nil {
+		return nil, fmt.Errorf("abi: changed mask sets %d arguments: %v", len(changed), err)
+	}
+	ret := make(map[int]interface{}, len(values))
+	for i, value := range values {
+		ret[indices[i]] = value
+	}
+	return ret, nil
+}
+
+// AssertPacked packs args and compares the result with expected. On mismatch
+// the error names the first 32 byte slot that differs along with its packed
+// and expected contents in hex, a missing slot showing as empty.
+func (arguments Arguments) AssertPacked(expected []byte, args ...interface{}) error {
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(packed, expected) {
+		return nil
+	}
+	slot := func(data []byte, i int) []byte {
+		if i*32 >= len(data) {
+			return nil
+		}
+		if (i+1)*32 > len(data) {
+			return data[i*32:]
+		}
+		return data[i*32 : (i+1)*32]
+	}
+	for i := 0; ; i++ {
+		if have, want := slot(packed, i), slot(expected, i); !bytes.Equal(have, want) {
+			return fmt.Errorf("abi: packed data differs at slot %d (offset %d, %d bytes packed, %d expected): have %x, want %x", i, i*32, len(packed), len(expected), have, want)
+		}
+	}
+}
+
+// headSize returns the number of bytes taken up by the heads of the arguments,
+// which is where the tail of the encoding starts.
+func (arguments Arguments) headSize() int {
+	size := 0
+	for _, abiArg := range arguments {
+		// Static arrays and tuples are encoded inline, everything dynamic
+		// takes a single word holding the offset of its tail.
+		size += getTypeSize(abiArg.Type)
+	}
+	return size
+}
+
+// pack packs the given values, with the tail starting at inputOffset.
+func (arguments Arguments) pack(inputOffset int, args []interface{}) ([]byte, error) {
 	// Make sure arguments match up and pack them
 	abiArgs := arguments
 	if len(args) != len(abiArgs) {
@@ -274,25 +766,17 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
 	// output. This is used for strings and bytes types input.
 	var variableInput []byte
 
-	// input offset is the bytes offset for packed output
-	inputOffset := 0
-	for _, abiArg := range abiArgs {
-		if abiArg.Type.T == ArrayTy {
-			inputOffset += 32 * abiArg.Type.Size
-		} else {
-			inputOffset += 32
-		}
-	}
 	var ret []byte
 	for i, a := range args {
 		input := abiArgs[i]
 		// pack the input
-		packed, err := input.Type.pack(reflect.ValueOf(a))
+		packed, err := input.pack(a)
 		if err != nil {
 			return nil, err
 		}
-		// check for a slice type (string, bytes, slice)
-		if input.Type.requiresLengthPrefix() {
+		// check for a dynamic type (string, bytes, slice, or an array or
+		// tuple containing one)
+		if isDynamicType(input.Type) {
 			// calculate the offset
 			offset := inputOffset + len(variableInput)
 			// set the offset
@@ -311,6 +795,126 @@ func (arguments Arguments) Pack(args ...interface{}) ([]byte, error) {
 	return ret, nil
 }
 
+// pack packs a single value, using the custom encoder if one is attached.
+func (argument Argument) pack(v interface{}) ([]byte, error) {
+	if argument.Encoder == nil {
+		return argument.Type.pack(reflect.ValueOf(v))
+	}
+	packed, err := argument.Encoder(v)
+	if err != nil {
+		return nil, err
+	}
+	if !isDynamicType(argument.Type) {
+		if size := getTypeSize(argument.Type); len(packed) != size {
+			return nil, fmt.Errorf("abi: custom encoder for %s returned %d bytes, want %d", argument.Name, len(packed), size)
+		}
+	}
+	return packed, nil
+}
+
+// FormatCall renders a call of the named method with the given values in a
+// human readable form, e.g. transfer(2Sx..., 1000000). Addresses are shown as
+// SERO base58 strings, integers in decimal and byte values as hex.
+func (arguments Arguments) FormatCall(name string, values ...interface{}) (string, error) {
+	if len(values) != len(arguments) {
+		return "", fmt.Errorf("argument count mismatch: %d for %d", len(values), len(arguments))
+	}
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		value := indirect(reflect.ValueOf(v))
+		if err := typeCheck(arguments[i].Type, value); err != nil {
+			return "", err
+		}
+		str, err := formatValue(arguments[i].Type, value)
+		if err != nil {
+			return "", err
+		}
+		formatted[i] = str
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(formatted, ", ")), nil
+}
+
+// formatValue renders a single value according to its abi type.
+func formatValue(t Type, v reflect.Value) (string, error) {
+	v = indirect(v)
+	switch t.T {
+	case SliceTy, ArrayTy:
+		elems := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			str, err := formatValue(*t.Elem, v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elems[i] = str
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case TupleTy:
+		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, v)
+		if err != nil {
+			return "", err
+		}
+		elems := make([]string, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
+			if !field.IsValid() {
+				return "", fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
+			}
+			str, err := formatValue(*elem, field)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = str
+		}
+		return "(" + strings.Join(elems, ", ") + ")", nil
+	case IntTy, UintTy:
+		if v.Kind() == reflect.Ptr {
+			return v.Interface().(*big.Int).String(), nil
+		}
+		return fmt.Sprintf("%d", v.Interface()), nil
+	case BoolTy:
+		return fmt.Sprintf("%t", v.Bool()), nil
+	case StringTy:
+		return fmt.Sprintf("%q", v.String()), nil
+	case AddressTy:
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		if v.Len() == common.AddressLength {
+			return common.BytesToAddress(v.Bytes()).String(), nil
+		}
+		return hexutil.Encode(v.Bytes()), nil
+	case BytesTy, FixedBytesTy, FunctionTy:
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		return hexutil.Encode(v.Bytes()), nil
+	default:
+		return "", fmt.Errorf("abi: cannot format type %v", t)
+	}
+}
+
+// PackStructPositional packs a struct into the tuple argument by field order,
+// the i-th struct field becomes the i-th tuple component whatever its name.
+// Nested tuples are still matched by name. It can be used as the argument's
+// Encoder for structs whose field names don't follow the abi.
+func (argument Argument) PackStructPositional(v interface{}) ([]byte, error) {
+	if argument.Type.T != TupleTy {
+		return nil, fmt.Errorf("abi: argument %s is not a tuple", argument.Name)
+	}
+	value := indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, typeErr(reflect.Struct, value.Kind())
+	}
+	if value.NumField() != len(argument.Type.TupleElems) {
+		return nil, fmt.Errorf("abi: struct has %d fields, tuple %s has %d components", value.NumField(), argument.Name, len(argument.Type.TupleElems))
+	}
+	fields := make([]reflect.Value, value.NumField())
+	for i := range fields {
+		fields[i] = value.Field(i)
+	}
+	return argument.Type.packTuple(fields)
+}
+
 // capitalise makes the first character of a string upper case, also removing any
 // prefixing underscores from the variable names.
 func capitalise(input string) string {
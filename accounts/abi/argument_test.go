@@ -0,0 +1,677 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+)
+
+// TestArgumentsConcurrentUse packs and unpacks with shared Arguments from many
+// goroutines, run with -race to catch hidden mutation of the parsed types.
+func TestArgumentsConcurrentUse(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"a","type":"uint256"},{"name":"b","type":"string"},{"name":"c","type":"tuple[]","components":[{"name":"x","type":"uint64"},{"name":"y","type":"bytes"}]}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	type tuple struct {
+		X uint64
+		Y []byte
+	}
+	values := []interface{}{big.NewInt(7), "sero", []tuple{{1, []byte{1}}, {2, []byte{2, 2}}}}
+	want, err := args.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				packed, err := args.Pack(values...)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(packed, want) {
+					t.Errorf("packed mismatch: got %x want %x", packed, want)
+					return
+				}
+				if _, err := args.UnpackValues(packed); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := args.UnpackValues(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].(*big.Int).Cmp(big.NewInt(7)) != 0 || got[1].(string) != "sero" {
+		t.Fatalf("unexpected values: %v", got)
+	}
+	if l := reflect.ValueOf(got[2]).Len(); l != 2 {
+		t.Fatalf("unexpected tuple count: %d", l)
+	}
+}
+
+func TestArgumentCustomEncoder(t *testing.T) {
+	uint256, _ := NewType("uint256", "", nil)
+	bytesT, _ := NewType("bytes", "", nil)
+	args := Arguments{
+		{Name: "a", Type: uint256},
+		{Name: "b", Type: bytesT, Encoder: func(v interface{}) ([]byte, error) {
+			// compress to a single byte and encode it as bytes
+			return packBytesSlice([]byte{v.([]byte)[0]}, 1), nil
+		}},
+		{Name: "c", Type: uint256, Encoder: func(v interface{}) ([]byte, error) {
+			return U256(big.NewInt(int64(len(v.(string))))), nil
+		}},
+	}
+	packed, err := args.Pack(big.NewInt(1), []byte{9, 8, 7}, "four")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := args.UnpackValues(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(values[1].([]byte), []byte{9}) {
+		t.Errorf("custom dynamic encoding mismatch: %x", values[1])
+	}
+	if values[2].(*big.Int).Int64() != 4 {
+		t.Errorf("custom static encoding mismatch: %v", values[2])
+	}
+
+	args[2].Encoder = func(interface{}) ([]byte, error) { return []byte{1}, nil }
+	if _, err := args.Pack(big.NewInt(1), []byte{9}, "x"); err == nil {
+		t.Error("expected error for short static encoding")
+	}
+}
+
+func TestUnpackMulticall(t *testing.T) {
+	uint256, _ := NewType("uint256", "", nil)
+	str, _ := NewType("string", "", nil)
+	bytesArr, _ := NewType("bytes[]", "", nil)
+
+	first := Arguments{{Name: "balance", Type: uint256}}
+	second := Arguments{{Name: "name", Type: str}, {Name: "decimals", Type: uint256}}
+	r0, err := first.Pack(big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, err := second.Pack("SERO", big.NewInt(18))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := Arguments{{Name: "results", Type: bytesArr}}
+	data, err := outer.Pack([][]byte{r0, r1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := outer.UnpackMulticall(data, []Arguments{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0][0].(*big.Int).Int64() != 100 {
+		t.Errorf("result 0 mismatch: %v", values[0])
+	}
+	if values[1][0].(string) != "SERO" || values[1][1].(*big.Int).Int64() != 18 {
+		t.Errorf("result 1 mismatch: %v", values[1])
+	}
+	if _, err := outer.UnpackMulticall(data, []Arguments{first}); err == nil {
+		t.Error("expected error for result count mismatch")
+	}
+}
+
+func TestFormatCall(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"ids","type":"uint8[]"}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	var to [96]byte
+	to[0] = 1
+	str, err := args.FormatCall("transfer", to, big.NewInt(1000000), []byte{0xca, 0xfe}, []uint8{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "transfer(" + common.BytesToAddress(to[:]).String() + ", 1000000, 0xcafe, [1, 2])"
+	if str != want {
+		t.Errorf("got %s, want %s", str, want)
+	}
+	if _, err := args.FormatCall("transfer", to); err == nil {
+		t.Error("expected error for value count mismatch")
+	}
+}
+
+func TestPreparedArguments(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"amount","type":"uint256"},{"name":"memo","type":"string"},{"name":"ids","type":"uint64[2]"},{"name":"ok","type":"bool"}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	prepared := args.Prepare()
+	values := []interface{}{big.NewInt(3), "memo", [2]uint64{4, 5}, true}
+	want, err := args.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := prepared.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("packed mismatch: got %x want %x", packed, want)
+	}
+	type result struct {
+		Amount *big.Int
+		Memo   string
+		Ids    [2]uint64
+		Ok     bool
+	}
+	for i := 0; i < 2; i++ {
+		var got, exp result
+		if err := prepared.Unpack(&got, packed); err != nil {
+			t.Fatal(err)
+		}
+		if err := args.Unpack(&exp, packed); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("unpack mismatch: got %+v want %+v", got, exp)
+		}
+	}
+
+	// without the field cache struct fields are resolved on every call
+	uncached := prepared
+	uncached.fields = nil
+	var got result
+	if err := uncached.Unpack(&got, packed); err != nil || got.Memo != "memo" {
+		t.Fatalf("without cache: have %+v %v", got, err)
+	}
+	var zero PreparedArguments
+	if err := zero.Unpack(&got, packed); err == nil {
+		t.Error("zero value: expected error for no arguments")
+	}
+	var missing struct {
+		Amount *big.Int
+		Memo   string
+		Ids    [2]uint64
+	}
+	if err := prepared.Unpack(&missing, packed); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("missing field: have %v, want an error of kind %v", err, ErrFieldNotFound)
+	}
+}
+
+func TestPackStructPositional(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "amount", Type: "uint256"}, {Name: "memo", Type: "string"}}
+	typ, err := NewType("tuple", "", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arg := Argument{Name: "transfer", Type: typ}
+	type named struct {
+		Amount *big.Int
+		Memo   string
+	}
+	type positional struct {
+		First  *big.Int
+		Second string
+	}
+	want, err := typ.pack(reflect.ValueOf(named{big.NewInt(5), "hi"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := arg.PackStructPositional(positional{big.NewInt(5), "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+	if _, err := typ.pack(reflect.ValueOf(positional{big.NewInt(5), "hi"})); err == nil {
+		t.Error("expected name based packing to fail")
+	}
+	if _, err := arg.PackStructPositional(struct{ A *big.Int }{big.NewInt(1)}); err == nil {
+		t.Error("expected error for field count mismatch")
+	}
+}
+
+func TestArgumentsAsNonIndexed(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	event := Arguments{{Name: "from", Type: uint256Type, Indexed: true}, {Name: "value", Type: uint256Type}}
+
+	plain := event.AsNonIndexed()
+	if len(plain.NonIndexed()) != 2 {
+		t.Fatalf("have %d non-indexed arguments, want 2", len(plain.NonIndexed()))
+	}
+	if !event[0].Indexed {
+		t.Fatal("receiver was modified")
+	}
+	packed, err := plain.Pack(big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := plain.UnpackValues(packed)
+	if err != nil || len(values) != 2 {
+		t.Fatalf("have %v %v, want both values", values, err)
+	}
+}
+
+func TestArgumentsExpectedTopics(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	event := Arguments{{Name: "from", Type: uint256Type, Indexed: true}, {Name: "to", Type: uint256Type, Indexed: true}, {Name: "value", Type: uint256Type}}
+	if n := event.ExpectedTopics(false); n != 3 {
+		t.Errorf("have %d topics, want 3", n)
+	}
+	if n := event.ExpectedTopics(true); n != 2 {
+		t.Errorf("anonymous: have %d topics, want 2", n)
+	}
+	if n := event.NonIndexed().ExpectedTopics(false); n != 1 {
+		t.Errorf("no indexed arguments: have %d topics, want 1", n)
+	}
+}
+
+func TestArgumentsPackTopics(t *testing.T) {
+	var event Arguments
+	def := `[{"name":"from","type":"address","indexed":true},{"name":"name","type":"string","indexed":true},{"name":"ids","type":"uint256[2]","indexed":true},{"name":"value","type":"uint256"}]`
+	if err := json.Unmarshal([]byte(def), &event); err != nil {
+		t.Fatal(err)
+	}
+	var from common.Address
+	from[0], from[95] = 1, 2
+	ids := [2]*big.Int{big.NewInt(1), big.NewInt(2)}
+	topics, err := event.PackTopics(from, "hello", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []common.Hash{
+		common.BytesToHash(convertToPkr(from[:])),
+		crypto.Keccak256Hash([]byte("hello")),
+		crypto.Keccak256Hash(common.LeftPadBytes([]byte{1}, 32), common.LeftPadBytes([]byte{2}, 32)),
+	}
+	if !reflect.DeepEqual(topics, want) {
+		t.Errorf("have %x, want %x", topics, want)
+	}
+	if _, err := event.PackTopics(from, "hello"); err == nil {
+		t.Error("expected error for a missing indexed value")
+	}
+	if _, err := event.PackTopics(from, 1, ids); err == nil {
+		t.Error("expected error for a mistyped indexed value")
+	}
+}
+
+func TestArgumentsByteRanges(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"a","type":"uint256"},{"name":"s","type":"string"},{"name":"arr","type":"uint256[2]"},{"name":"b","type":"bytes[]"}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	packed, err := args.Pack(big.NewInt(1), "hi", [2]*big.Int{big.NewInt(2), big.NewInt(3)}, [][]byte{{1, 2, 3}, make([]byte, 33)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges, err := args.ByteRanges(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ArgByteRange{
+		{Name: "a", HeadStart: 0, HeadEnd: 32},
+		{Name: "s", HeadStart: 32, HeadEnd: 64, Dynamic: true, TailStart: 160, TailEnd: 224},
+		{Name: "arr", HeadStart: 64, HeadEnd: 128},
+		// count, two offsets, then 32+32 and 32+64 bytes for the elements
+		{Name: "b", HeadStart: 128, HeadEnd: 160, Dynamic: true, TailStart: 224, TailEnd: 224 + 96 + 64 + 96},
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("have %+v, want %+v", ranges, want)
+	}
+	if ranges[3].TailEnd != len(packed) {
+		t.Errorf("last tail ends at %d, packed data has %d bytes", ranges[3].TailEnd, len(packed))
+	}
+
+	if _, err := args.ByteRanges(packed[:100]); err == nil {
+		t.Error("expected error for truncated heads")
+	}
+	if _, err := args.ByteRanges(packed[:len(packed)-1]); err == nil {
+		t.Error("expected error for a truncated tail")
+	}
+	corrupt := common.CopyBytes(packed)
+	corrupt[63] = 0xff
+	if _, err := args.ByteRanges(corrupt); err == nil {
+		t.Error("expected error for an offset past the end")
+	}
+}
+
+func TestABIPackCall(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var to common.Address
+	to[0], to[95] = 1, 2
+	packed, err := abi.PackCall("transfer", to, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("a9059cbb")
+	want = append(want, convertToPkr(to[:])...)
+	want = append(want, common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)...)
+	if !bytes.Equal(packed, want) {
+		t.Errorf("have %x, want %x", packed, want)
+	}
+	if _, err := abi.PackCall("transfer", to); err == nil {
+		t.Error("expected error for a missing argument")
+	}
+	if _, err := abi.PackCall("approve", to, big.NewInt(1)); err == nil {
+		t.Error("expected error for an unknown method")
+	}
+	if _, err := abi.PackCall(""); err == nil {
+		t.Error("expected error for an empty method name")
+	}
+}
+
+func TestABIPackAliasing(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"pause","inputs":[]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the result of a call without arguments is not backed by the method ID,
+	// so appending to it doesn't show in the result of the next call
+	first, _ := abi.Pack("pause")
+	first = append(first, 1)
+	second, _ := abi.Pack("pause")
+	second = append(second, 2)
+	if first[4] != 1 || !bytes.Equal(second[:4], abi.Methods["pause"].ID) {
+		t.Errorf("results share memory: have %x and %x", first, second)
+	}
+}
+
+func TestABIPackPrefixAddressSlices(t *testing.T) {
+	abi, err := JSON(strings.NewReader(`[{"type":"function","name":"airdrop","inputs":[{"name":"to","type":"address[]"},{"name":"groups","type":"address[2][]"},{"name":"value","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkr := func(b byte) (ret c_type.PKr) {
+		ret[0], ret[95] = b, b
+		return
+	}
+	var rand c_type.Uint128
+	rand[0] = 9
+	prefix, err := abi.PackPrefix("airdrop", rand, []c_type.PKr{pkr(1), pkr(2)}, [][2]c_type.PKr{{pkr(3), pkr(4)}}, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(prefix[:len(rand)], rand[:]) {
+		t.Fatalf("have rand %x, want %x", prefix[:len(rand)], rand[:])
+	}
+	pkrs, err := UnpackPrefix(prefix[len(rand):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []c_type.PKr{pkr(1), pkr(2), pkr(3), pkr(4)}; !reflect.DeepEqual(pkrs, want) {
+		t.Errorf("have %d PKrs %x, want the 4 passed in order", len(pkrs), pkrs)
+	}
+	if _, err := abi.PackPrefix("airdrop", rand, [][]byte{{1}}, [][2]c_type.PKr{}, big.NewInt(1)); err == nil {
+		t.Error("expected error for an address that is not a PKr")
+	}
+}
+
+func TestABIDuplicateArgumentNames(t *testing.T) {
+	def := `[{"type":"function","name":"balances","outputs":[{"name":"value","type":"uint256"},{"name":"value","type":"uint64"}]}]`
+	_, err := JSON(strings.NewReader(def))
+	if err == nil || !strings.Contains(err.Error(), `duplicate argument name "value"`) || !strings.Contains(err.Error(), "balances") {
+		t.Fatalf("have %v, want a duplicate name error for balances", err)
+	}
+	def = `[{"type":"event","name":"Sent","inputs":[{"name":"to","type":"address","indexed":true},{"name":"to","type":"uint256"}]}]`
+	if _, err := JSON(strings.NewReader(def)); err == nil {
+		t.Error("expected error for duplicate event inputs")
+	}
+	def = `[{"type":"function","name":"pair","outputs":[{"name":"","type":"uint256"},{"name":"","type":"uint256"}]}]`
+	if _, err := JSON(strings.NewReader(def)); err != nil {
+		t.Errorf("unnamed outputs: %v", err)
+	}
+}
+
+func TestArgumentsCanonicalTypes(t *testing.T) {
+	tests := []struct {
+		def  string
+		want string
+	}{
+		{`[]`, `()`},
+		{`[{"name":"a","type":"uint"},{"name":"b","type":"address[]"},{"name":"c","type":"bytes"}]`, `(uint256,address[],bytes)`},
+		{`[{"name":"a","type":"int[2][]"},{"name":"b","type":"bytes32[3]"}]`, `(int256[2][],bytes32[3])`},
+		{`[{"name":"order","type":"tuple","components":[{"name":"maker","type":"address"},{"name":"fills","type":"tuple[]","components":[{"name":"amount","type":"uint"},{"name":"memo","type":"string"}]}]},{"name":"ok","type":"bool"}]`,
+			`((address,(uint256,string)[]),bool)`},
+		{`[{"name":"pairs","type":"tuple[2][]","components":[{"name":"x","type":"uint8"},{"name":"y","type":"bytes[]"}]}]`, `((uint8,bytes[])[2][])`},
+	}
+	for _, test := range tests {
+		var args Arguments
+		if err := json.Unmarshal([]byte(test.def), &args); err != nil {
+			t.Fatal(err)
+		}
+		if have := args.CanonicalTypes(); have != test.want {
+			t.Errorf("%s: have %s, want %s", test.def, have, test.want)
+		}
+	}
+}
+
+func TestArgumentsCopy(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"to","type":"address","indexed":true},{"name":"items","type":"tuple[]","components":[{"name":"x","type":"uint8"}]}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	cpy := args.Copy()
+	cpy[0].Name, cpy[0].Indexed = "recipient", false
+	cpy = append(cpy[:1], Argument{Name: "extra", Type: args[1].Type})
+	if args[0].Name != "to" || !args[0].Indexed || args[1].Name != "items" {
+		t.Fatalf("original modified through its copy: %+v", args)
+	}
+	if cpy[1].Type.Elem != args[1].Type.Elem {
+		t.Error("copy does not share the parsed type")
+	}
+	if Arguments(nil).Copy() != nil {
+		t.Error("copy of nil arguments is not nil")
+	}
+}
+
+func TestArgumentsSameSelector(t *testing.T) {
+	addrType, _ := NewType("address", "", nil)
+	uint256Type, _ := NewType("uint256", "", nil)
+	uintType, _ := NewType("uint", "", nil)
+	uint128Type, _ := NewType("uint128", "", nil)
+
+	v1 := Arguments{{Name: "to", Type: addrType}, {Name: "value", Type: uint256Type}}
+	renamed := Arguments{{Name: "recipient", Type: addrType}, {Name: "amount", Type: uintType}}
+	retyped := Arguments{{Name: "to", Type: addrType}, {Name: "value", Type: uint128Type}}
+
+	if !v1.SameSelector("transfer", renamed, "transfer") {
+		t.Error("renamed arguments should keep the selector")
+	}
+	if v1.SameSelector("transfer", retyped, "transfer") {
+		t.Error("retyped argument should change the selector")
+	}
+	if v1.SameSelector("transfer", v1, "send") {
+		t.Error("renamed method should change the selector")
+	}
+	method := NewMethod("transfer", "transfer", Function, "", false, false, v1, nil)
+	if !bytes.Equal(v1.selector("transfer"), method.ID) {
+		t.Errorf("selector %x differs from method id %x", v1.selector("transfer"), method.ID)
+	}
+}
+
+func TestUnpackPrefix(t *testing.T) {
+	addrType, _ := NewType("address", "", nil)
+	addrsType, _ := NewType("address[]", "", nil)
+	args := Arguments{{Name: "to", Type: addrType}, {Name: "others", Type: addrsType}}
+
+	var a, b, c common.Address
+	a[0], b[0], c[0] = 1, 2, 3
+	prefix, err := args.PackPrefix(a, []common.Address{b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkrs, err := UnpackPrefix(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkrs) != 3 || pkrs[0][0] != 1 || pkrs[1][0] != 2 || pkrs[2][0] != 3 {
+		t.Fatalf("have %v, want the three packed PKrs", pkrs)
+	}
+	if _, err := UnpackPrefix(prefix[:len(prefix)-1]); err == nil {
+		t.Error("expected error for truncated prefix")
+	}
+	if _, err := UnpackPrefix(prefix[:1]); err == nil {
+		t.Error("expected error for missing count")
+	}
+	if pkrs, err := UnpackPrefix([]byte{0, 0}); err != nil || len(pkrs) != 0 {
+		t.Errorf("empty prefix: have %v %v", pkrs, err)
+	}
+}
+
+func TestArgumentsAssertPacked(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "value", Type: uint256Type}, {Name: "memo", Type: stringType}}
+
+	expected, err := args.Pack(big.NewInt(1), "sero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := args.AssertPacked(expected, big.NewInt(1), "sero"); err != nil {
+		t.Fatal(err)
+	}
+	err = args.AssertPacked(expected, big.NewInt(1), "SERO")
+	if err == nil || !strings.Contains(err.Error(), "slot 3 ") || !strings.Contains(err.Error(), "have 5345524f") {
+		t.Errorf("have %v, want a diff of slot 3", err)
+	}
+	err = args.AssertPacked(expected[:96], big.NewInt(1), "sero")
+	if err == nil || !strings.Contains(err.Error(), "slot 3 ") || !strings.HasSuffix(err.Error(), "want ") {
+		t.Errorf("have %v, want slot 3 missing from the expectation", err)
+	}
+}
+
+func TestArgumentsPackChanged(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	bytesType, _ := NewType("bytes", "", nil)
+	var args Arguments
+	for i := 0; i < 3; i++ {
+		args = append(args, Argument{Name: "n", Type: uint256Type}, Argument{Name: "s", Type: stringType}, Argument{Name: "b", Type: bytesType})
+	}
+	baseline := []interface{}{big.NewInt(1), "a", []byte{1}, big.NewInt(2), "b", []byte{2}, big.NewInt(3), "c", []byte{3}}
+	updated := []interface{}{big.NewInt(1), "a", []byte{1}, big.NewInt(20), "b", []byte{2}, big.NewInt(3), "c", []byte{4}}
+
+	mask, packed, err := args.PackChanged(baseline, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x01, 0x08}; !bytes.Equal(mask, want) {
+		t.Errorf("have mask %x, want %x", mask, want)
+	}
+	want, _ := Arguments{args[3], args[8]}.Pack(big.NewInt(20), []byte{4})
+	if !bytes.Equal(packed, want) {
+		t.Errorf("have %x, want %x", packed, want)
+	}
+
+	mask, packed, err = args.PackChanged(baseline, baseline)
+	if err != nil || !bytes.Equal(mask, []byte{0, 0}) || len(packed) != 0 {
+		t.Errorf("unchanged: have %x %x %v", mask, packed, err)
+	}
+	if _, _, err := args.PackChanged(baseline, updated[:3]); err == nil {
+		t.Error("expected count mismatch error")
+	}
+}
+
+func TestArgumentsUnpackChanged(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "a", Type: uint256Type}, {Name: "b", Type: stringType}, {Name: "c", Type: uint256Type}}
+
+	baseline := []interface{}{big.NewInt(1), "a", big.NewInt(3)}
+	updated := []interface{}{big.NewInt(1), "b", big.NewInt(30)}
+	mask, packed, err := args.PackChanged(baseline, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := args.UnpackChanged(mask, packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[1] != "b" || values[2].(*big.Int).Int64() != 30 {
+		t.Fatalf("have %v, want arguments 1 and 2", values)
+	}
+	if values, err := args.UnpackChanged([]byte{0}, nil); err != nil || len(values) != 0 {
+		t.Errorf("nothing changed: have %v %v", values, err)
+	}
+	for _, test := range []struct {
+		mask   []byte
+		packed []byte
+	}{
+		{[]byte{0, 6}, packed},          // mask too long
+		{[]byte{0x08}, packed},          // bit past the arguments
+		{[]byte{0x07}, packed},          // more bits than packed values
+		{[]byte{0x00}, packed},          // values without bits
+		{mask, packed[:len(packed)-32]}, // truncated values
+	} {
+		if _, err := args.UnpackChanged(test.mask, test.packed); err == nil {
+			t.Errorf("mask %x with %d bytes: expected error", test.mask, len(test.packed))
+		}
+	}
+}
+
+func TestArgumentsUnpackValuesAt(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "n", Type: uint256Type}, {Name: "memo", Type: stringType}}
+
+	packed, err := args.Pack(big.NewInt(5), "embedded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(bytes.Repeat([]byte{0xaa}, 36), packed...)
+	values, err := args.UnpackValuesAt(data, 36)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].(*big.Int).Int64() != 5 || values[1] != "embedded" {
+		t.Errorf("have %v, want 5 and embedded", values)
+	}
+	if _, err := args.UnpackValuesAt(data, len(data)+1); err == nil {
+		t.Error("expected error for base past the data")
+	}
+	if _, err := args.UnpackValuesAt(data, -1); err == nil {
+		t.Error("expected error for negative base")
+	}
+}
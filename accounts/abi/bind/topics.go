@@ -104,10 +104,22 @@ var (
 	reflectBigInt  = reflect.TypeOf(new(big.Int))
 )
 
+// isHashedTopic reports whether an indexed argument of the given type is stored
+// in its topic as the Keccak256 hash of its encoding rather than the value.
+func isHashedTopic(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
 // parseTopics converts the indexed topic fields into actual log field values.
 //
 // Note, dynamic types cannot be reconstructed since they get mapped to Keccak256
-// hashes as the topic value!
+// hashes as the topic value! The preimage is not recoverable from the log, so
+// such fields must be declared as common.Hash or [32]byte and receive the raw
+// topic hash.
 func parseTopics(out interface{}, fields abi.Arguments, topics []common.Hash) error {
 	// Sanity check that the fields and topics match up
 	if len(fields) != len(topics) {
@@ -120,6 +132,13 @@ func parseTopics(out interface{}, fields abi.Arguments, topics []common.Hash) er
 		}
 		field := reflect.ValueOf(out).Elem().FieldByName(capitalise(arg.Name))
 
+		if isHashedTopic(arg.Type) {
+			if err := setTopicHash(field, arg, topics[0]); err != nil {
+				return err
+			}
+			topics = topics[1:]
+			continue
+		}
 		// Try to parse the topic back into the fields based on primitive types
 		switch field.Kind() {
 		case reflect.Bool:
@@ -188,6 +207,23 @@ func parseTopics(out interface{}, fields abi.Arguments, topics []common.Hash) er
 	return nil
 }
 
+// setTopicHash assigns the raw topic hash of a hashed indexed argument to its
+// destination field, which must be able to hold a 32 byte hash.
+func setTopicHash(field reflect.Value, arg abi.Argument, topic common.Hash) error {
+	switch {
+	case !field.IsValid():
+		return fmt.Errorf("bind: no field for indexed argument %q", arg.Name)
+	case field.Type() == reflectHash:
+		field.Set(reflect.ValueOf(topic))
+	case field.Kind() == reflect.Array && field.Type().Elem().Kind() == reflect.Uint8 && field.Len() == common.HashLength:
+		reflect.Copy(field, reflect.ValueOf(topic[:]))
+	default:
+		return fmt.Errorf("bind: indexed %v argument %q is stored as its keccak256 hash, cannot assign to %v (use common.Hash or [32]byte)",
+			arg.Type, arg.Name, field.Type())
+	}
+	return nil
+}
+
 // parseTopicsIntoMap converts the indexed topic field-value pairs into map key-value pairs
 func parseTopicsIntoMap(out map[string]interface{}, fields abi.Arguments, topics []common.Hash) error {
 	// Sanity check that the fields and topics match up
@@ -0,0 +1,57 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-sero/accounts/abi"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+)
+
+func TestParseTopicsIndexedString(t *testing.T) {
+	stringType, err := abi.NewType("string", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := abi.Arguments{{Name: "name", Type: stringType, Indexed: true}}
+	topic := crypto.Keccak256Hash([]byte("sero"))
+
+	var byHash struct{ Name common.Hash }
+	if err := parseTopics(&byHash, fields, []common.Hash{topic}); err != nil {
+		t.Fatalf("common.Hash destination: %v", err)
+	}
+	if byHash.Name != topic {
+		t.Errorf("common.Hash destination: have %x, want %x", byHash.Name, topic)
+	}
+
+	var byArray struct{ Name [32]byte }
+	if err := parseTopics(&byArray, fields, []common.Hash{topic}); err != nil {
+		t.Fatalf("[32]byte destination: %v", err)
+	}
+	if byArray.Name != topic {
+		t.Errorf("[32]byte destination: have %x, want %x", byArray.Name, topic)
+	}
+
+	var byString struct{ Name string }
+	err = parseTopics(&byString, fields, []common.Hash{topic})
+	if err == nil || !strings.Contains(err.Error(), "keccak256 hash") {
+		t.Fatalf("string destination: have error %v, want hash error", err)
+	}
+}
@@ -19,6 +19,7 @@ package abi
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 )
 
@@ -26,6 +27,49 @@ var (
 	errBadBool = errors.New("abi: improperly encoded boolean value")
 )
 
+// Unpacking errors can be told apart by their kind with errors.Is, e.g.
+// errors.Is(err, ErrInsufficientData). Their messages describe the failure
+// in detail.
+var (
+	// ErrInsufficientData is the kind of errors about data that is too short
+	// for the values it is unpacked as, or holds offsets or lengths pointing
+	// past its end.
+	ErrInsufficientData = errors.New("abi: insufficient data")
+	// ErrTypeMismatch is the kind of errors about decoded values that can not
+	// be stored in the Go value they are unpacked into.
+	ErrTypeMismatch = errors.New("abi: type mismatch")
+	// ErrFieldNotFound is the kind of errors about arguments or tuple fields
+	// that have no matching field in the struct they are unpacked into.
+	ErrFieldNotFound = errors.New("abi: field not found")
+	// ErrTupleMismatch is the kind of errors about multiple return values that
+	// are unpacked into a value that can not hold them all.
+	ErrTupleMismatch = errors.New("abi: tuple mismatch")
+)
+
+// unpackError is an error of one of the unpacking error kinds.
+type unpackError struct {
+	kind error
+	msg  string
+}
+
+func (e *unpackError) Error() string { return e.msg }
+
+func (e *unpackError) Unwrap() error { return e.kind }
+
+// unpackErrorf formats an error of the given kind, keeping the message as is.
+func unpackErrorf(kind error, format string, args ...interface{}) error {
+	return &unpackError{kind, fmt.Sprintf(format, args...)}
+}
+
+// prefixUnpackError adds context to the message of err, keeping its kind.
+func prefixUnpackError(err error, format string, args ...interface{}) error {
+	prefix := fmt.Sprintf(format, args...)
+	if e, ok := err.(*unpackError); ok {
+		return &unpackError{e.kind, prefix + e.msg}
+	}
+	return fmt.Errorf("%s%v", prefix, err)
+}
+
 // formatSliceString formats the reflection kind with the given slice size
 // and returns a formatted string representation.
 func formatSliceString(kind reflect.Kind, sliceSize int) string {
@@ -52,18 +96,49 @@ func sliceTypeCheck(t Type, val reflect.Value) error {
 		}
 	}
 
+	if (t.Elem.T == IntTy || t.Elem.T == UintTy) && isIntegerType(val.Type().Elem()) {
+		// elements are converted to the integer type one by one when packed
+		return nil
+	}
+	if t.Elem.T == FixedBytesTy && isByteSliceType(val.Type().Elem()) {
+		// the length of each element is checked when it is packed
+		return nil
+	}
 	if elemKind := val.Type().Elem().Kind(); elemKind != t.Elem.getType().Kind() {
 		return typeErr(formatSliceString(t.Elem.getType().Kind(), t.Size), val.Type())
 	}
 	return nil
 }
 
+// isIntegerType reports whether values of typ can be packed as any integer
+// type, range permitting: Go integers of any size and *big.Int.
+func isIntegerType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return typ == reflect.TypeOf(new(big.Int))
+}
+
+// isByteSliceType reports whether values of typ can be packed as a bytesN of
+// their length.
+func isByteSliceType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
 // typeCheck checks that the given reflection value can be assigned to the reflection
 // type in t.
 func typeCheck(t Type, value reflect.Value) error {
 	if t.T == SliceTy || t.T == ArrayTy {
 		return sliceTypeCheck(t, value)
 	}
+	if t.T == FixedBytesTy && value.IsValid() && isByteSliceType(value.Type()) {
+		if value.Len() != t.Size {
+			return fmt.Errorf("abi: cannot use %d byte slice as %v", value.Len(), t)
+		}
+		return nil
+	}
 
 	// Check base type validity. Element types will be checked later on.
 	if t.getType().Kind() != value.Kind() {
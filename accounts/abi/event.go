@@ -64,6 +64,7 @@ func NewEvent(name, rawName string, anonymous bool, inputs Arguments) Event {
 				Name:    fmt.Sprintf("arg%d", i),
 				Indexed: input.Indexed,
 				Type:    input.Type,
+				Encoder: input.Encoder,
 			}
 		} else {
 			inputs[i] = input
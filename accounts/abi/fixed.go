@@ -0,0 +1,89 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+// RoundingMode selects how ScaleFixedPoint handles digits beyond the scale of
+// a fixed point type.
+type RoundingMode int
+
+const (
+	// RoundTruncate drops the extra digits, rounding toward zero.
+	RoundTruncate RoundingMode = iota
+	// RoundHalfUp rounds to the nearest value, halves away from zero.
+	RoundHalfUp
+)
+
+// ScaleFixedPoint converts a human readable decimal into the integer encoding
+// of a fixed<M>x<N> (signed) or ufixed<M>x<N> value, i.e. the value scaled by
+// 10^N. The value may be a decimal string such as "1.5", a *big.Rat, a
+// *big.Float or a *big.Int. Digits beyond N decimals are rounded per mode and
+// an error is returned if the scaled value doesn't fit in M bits.
+//
+// The packer has no fixed point types yet, the result is meant to be passed
+// as the pre-scaled integer until it does.
+func ScaleFixedPoint(value interface{}, m, n int, signed bool, mode RoundingMode) (*big.Int, error) {
+	if m <= 0 || m > 256 || m%8 != 0 || n < 0 || n > 80 {
+		return nil, fmt.Errorf("abi: invalid fixed point type %dx%d", m, n)
+	}
+	var r *big.Rat
+	switch v := value.(type) {
+	case string:
+		var ok bool
+		if r, ok = new(big.Rat).SetString(v); !ok {
+			return nil, fmt.Errorf("abi: invalid decimal %q", v)
+		}
+	case *big.Rat:
+		r = v
+	case *big.Float:
+		if v.IsInf() {
+			return nil, fmt.Errorf("abi: cannot scale infinite value")
+		}
+		r, _ = v.Rat(nil)
+	case *big.Int:
+		r = new(big.Rat).SetInt(v)
+	default:
+		return nil, fmt.Errorf("abi: cannot scale %T to a fixed point value", value)
+	}
+	num := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+	scaled, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if mode == RoundHalfUp && new(big.Int).Lsh(rem.Abs(rem), 1).Cmp(r.Denom()) >= 0 {
+		scaled.Add(scaled, big.NewInt(int64(num.Sign())))
+	}
+
+	var min, max *big.Int
+	if signed {
+		max = new(big.Int).Lsh(common.Big1, uint(m-1))
+		min = new(big.Int).Neg(max)
+	} else {
+		min, max = common.Big0, new(big.Int).Lsh(common.Big1, uint(m))
+	}
+	if scaled.Cmp(min) < 0 || scaled.Cmp(max) >= 0 {
+		kind := "ufixed"
+		if signed {
+			kind = "fixed"
+		}
+		return nil, fmt.Errorf("abi: value %v overflows %s%dx%d", r.FloatString(n), kind, m, n)
+	}
+	return scaled, nil
+}
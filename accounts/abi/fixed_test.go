@@ -0,0 +1,71 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestScaleFixedPoint(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		m, n   int
+		signed bool
+		mode   RoundingMode
+		want   string
+	}{
+		{"1.5", 8, 1, false, RoundTruncate, "15"},
+		{"1.25", 8, 1, false, RoundTruncate, "12"},
+		{"1.25", 8, 1, false, RoundHalfUp, "13"},
+		{"1.249", 8, 1, false, RoundHalfUp, "12"},
+		{"-1.25", 8, 1, true, RoundHalfUp, "-13"},
+		{"-1.25", 8, 1, true, RoundTruncate, "-12"},
+		{big.NewRat(1, 3), 128, 18, false, RoundHalfUp, "333333333333333333"},
+		{big.NewRat(2, 3), 128, 18, false, RoundHalfUp, "666666666666666667"},
+		{big.NewFloat(0.5), 8, 0, false, RoundHalfUp, "1"},
+		{big.NewInt(3), 16, 2, false, RoundTruncate, "300"},
+	}
+	for i, test := range tests {
+		got, err := ScaleFixedPoint(test.value, test.m, test.n, test.signed, test.mode)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("test %d: have %v, want %v", i, got, test.want)
+		}
+	}
+	if got, err := ScaleFixedPoint("25.54", 8, 1, false, RoundHalfUp); err != nil || got.Int64() != 255 {
+		t.Errorf("largest ufixed8x1: have %v %v, want 255", got, err)
+	}
+	if got, err := ScaleFixedPoint("25.55", 8, 1, false, RoundHalfUp); err == nil {
+		t.Errorf("rounding past ufixed8x1: have %v, want overflow", got)
+	}
+	if got, err := ScaleFixedPoint("25.55", 8, 1, false, RoundTruncate); err != nil || got.Int64() != 255 {
+		t.Errorf("truncating to ufixed8x1: have %v %v, want 255", got, err)
+	}
+	if _, err := ScaleFixedPoint("-0.1", 8, 1, false, RoundTruncate); err == nil {
+		t.Error("expected error for negative ufixed")
+	}
+	if got, err := ScaleFixedPoint("-12.8", 8, 1, true, RoundTruncate); err != nil || got.Int64() != -128 {
+		t.Errorf("smallest fixed8x1: have %v %v, want -128", got, err)
+	}
+	if _, err := ScaleFixedPoint("abc", 8, 1, false, RoundTruncate); err == nil {
+		t.Error("expected error for invalid decimal")
+	}
+}
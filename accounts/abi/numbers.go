@@ -36,7 +36,12 @@ var (
 //addressT  = reflect.TypeOf(common.ContractAddress{})
 )
 
+// slotSize is the size in bytes of a single ABI word. The packer and unpacker
+// assume 32 byte slots throughout; numbers_test.go checks this against the
+// sizes of the types that are encoded into a slot.
+const slotSize = 32
+
 // U256 converts a big Int into a 256bit EVM number.
 func U256(n *big.Int) []byte {
-	return math.PaddedBigBytes(math.U256(n), 32)
+	return math.PaddedBigBytes(math.U256(n), slotSize)
 }
@@ -0,0 +1,43 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+)
+
+func TestSlotSize(t *testing.T) {
+	if l := len(c_type.Uint256{}); l != slotSize {
+		t.Fatalf("c_type.Uint256 is %d bytes, slot size is %d", l, slotSize)
+	}
+	if common.HashLength != slotSize {
+		t.Fatalf("common.Hash is %d bytes, slot size is %d", common.HashLength, slotSize)
+	}
+	if l := len(U256(MaxUint256)); l != slotSize {
+		t.Fatalf("U256 packs into %d bytes, slot size is %d", l, slotSize)
+	}
+	// Addresses are packed as the short hash of the PKr, left padded into a single slot.
+	if l := len(common.ContractAddress{}); l > slotSize {
+		t.Fatalf("contract address is %d bytes, does not fit into a %d byte slot", l, slotSize)
+	}
+	if l := len(convertToPkr(make([]byte, len(c_type.PKr{})))); l != slotSize {
+		t.Fatalf("packed address is %d bytes, slot size is %d", l, slotSize)
+	}
+}
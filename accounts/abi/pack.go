@@ -17,14 +17,17 @@
 package abi
 
 import (
+	"fmt"
 	"math/big"
 	"reflect"
+	"sort"
 
 	"github.com/sero-cash/go-czero-import/c_type"
 
 	"github.com/sero-cash/go-czero-import/c_superzk"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/math"
+	"github.com/sero-cash/go-sero/zero/utils"
 )
 
 // packBytesSlice packs the given bytes as [L, V] as the canonical representation
@@ -42,6 +45,49 @@ func convertToPkr(addr []byte) []byte {
 
 }
 
+// AddressForm selects the representation PackAddressForm writes for an
+// address.
+type AddressForm int
+
+const (
+	// AddressFormPKr takes a full 96 byte PKr and writes its 20 byte short
+	// hash, exactly as Pack encodes address arguments. It is the form to use
+	// for user addresses, which contracts only ever see hashed.
+	AddressFormPKr AddressForm = iota
+	// AddressFormContract takes a 20 byte contract address and writes it as
+	// is. It is the form to use for contract addresses and for re-encoding
+	// addresses returned by Unpack, which are already in their short form.
+	AddressFormContract
+)
+
+// PackAddressForm packs a single address word in the requested form. The
+// address may be given as a byte array or slice. To use a form other than the
+// default for an argument, wrap it in a function set as the Encoder.
+func PackAddressForm(addr interface{}, form AddressForm) ([]byte, error) {
+	value := indirect(reflect.ValueOf(addr))
+	if !value.IsValid() || (value.Kind() != reflect.Array && value.Kind() != reflect.Slice) || value.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, fmt.Errorf("abi: cannot pack %T as an address", addr)
+	}
+	if value.Kind() == reflect.Array {
+		value = mustArrayToByteSlice(value)
+	}
+	b := value.Bytes()
+	switch form {
+	case AddressFormPKr:
+		if len(b) != common.AddressLength {
+			return nil, fmt.Errorf("abi: PKr address must be %d bytes, got %d", common.AddressLength, len(b))
+		}
+		return convertToPkr(b), nil
+	case AddressFormContract:
+		if len(b) != len(common.ContractAddress{}) {
+			return nil, fmt.Errorf("abi: contract address must be %d bytes, got %d", len(common.ContractAddress{}), len(b))
+		}
+		return common.LeftPadBytes(b, 32), nil
+	default:
+		return nil, fmt.Errorf("abi: unknown address form %d", form)
+	}
+}
+
 // packElement packs the given reflect value according to the abi specification in
 // t.
 func packElement(t Type, reflectValue reflect.Value) []byte {
@@ -75,6 +121,79 @@ func packElement(t Type, reflectValue reflect.Value) []byte {
 	}
 }
 
+// checkIntRange checks that the given integer value fits into the bit width
+// and signedness of t.
+func checkIntRange(t Type, value reflect.Value) error {
+	var n *big.Int
+	switch kind := value.Kind(); kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = new(big.Int).SetUint64(value.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = big.NewInt(value.Int())
+	case reflect.Ptr:
+		n = value.Interface().(*big.Int)
+	default:
+		return typeErr(t.getType(), value.Type())
+	}
+	if t.T == UintTy {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return fmt.Errorf("abi: value %v overflows uint%d", n, t.Size)
+		}
+		return nil
+	}
+	max := new(big.Int).Lsh(common.Big1, uint(t.Size-1))
+	min := new(big.Int).Neg(max)
+	max.Sub(max, common.Big1)
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return fmt.Errorf("abi: value %v overflows int%d", n, t.Size)
+	}
+	return nil
+}
+
+// coerceInt converts a Go integer of any size or signedness, or a *big.Int,
+// given for an integer type into the Go type that type packs from, once it is
+// known to be in range. This lets e.g. an int be passed for a uint256 or a
+// *big.Int for a uint64. Other values are left for the type check.
+func coerceInt(t Type, v reflect.Value) (reflect.Value, error) {
+	if t.T != IntTy && t.T != UintTy || !v.IsValid() {
+		return v, nil
+	}
+	target := t.getType()
+	if v.Kind() == target.Kind() {
+		return v, nil
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Ptr:
+		if v.Type() != reflect.TypeOf(new(big.Int)) {
+			return v, nil
+		}
+	default:
+		return v, nil
+	}
+	if err := checkIntRange(t, v); err != nil {
+		return v, err
+	}
+	var n *big.Int
+	switch kind := v.Kind(); kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = new(big.Int).SetUint64(v.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = big.NewInt(v.Int())
+	default:
+		n = v.Interface().(*big.Int)
+	}
+	switch target.Kind() {
+	case reflect.Ptr:
+		return reflect.ValueOf(n), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(n.Uint64()).Convert(target), nil
+	default:
+		return reflect.ValueOf(n.Int64()).Convert(target), nil
+	}
+}
+
 // packNum packs the given number (using the reflect value) and will cast it to appropriate number representation
 func packNum(value reflect.Value) []byte {
 	switch kind := value.Kind(); kind {
@@ -83,9 +202,27 @@ func packNum(value reflect.Value) []byte {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return U256(big.NewInt(value.Int()))
 	case reflect.Ptr:
-		return U256(value.Interface().(*big.Int))
+		// U256 works in place, copy so the caller's value is left untouched
+		return U256(new(big.Int).Set(value.Interface().(*big.Int)))
 	default:
 		panic("abi: fatal error")
 	}
 
 }
+
+// PackTokenMap flattens a token to amount map into parallel currency and
+// amount slices, ordered by currency, ready to be packed as a bytes32[] and a
+// uint256[] argument. The ordering makes the encoding of a map reproducible.
+func PackTokenMap(m map[c_type.Uint256]utils.U256) (currencies []c_type.Uint256, amounts []*big.Int) {
+	currencies = make([]c_type.Uint256, 0, len(m))
+	for currency := range m {
+		currencies = append(currencies, currency)
+	}
+	sort.Sort(utils.Uint256s(currencies))
+	amounts = make([]*big.Int, len(currencies))
+	for i, currency := range currencies {
+		amount := m[currency]
+		amounts[i] = amount.ToIntRef()
+	}
+	return currencies, amounts
+}
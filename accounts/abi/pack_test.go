@@ -0,0 +1,531 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func bigPow(base, exp, add int64) *big.Int {
+	r := new(big.Int).Exp(big.NewInt(base), big.NewInt(exp), nil)
+	return r.Add(r, big.NewInt(add))
+}
+
+func TestPackIntRange(t *testing.T) {
+	for i, test := range []struct {
+		typ   string
+		value *big.Int
+		ok    bool
+	}{
+		{"uint24", bigPow(2, 24, -1), true},
+		{"uint24", bigPow(2, 24, 0), false},
+		{"uint24", big.NewInt(-1), false},
+		{"uint72", bigPow(2, 72, -1), true},
+		{"uint72", bigPow(2, 72, 0), false},
+		{"uint256", bigPow(2, 256, -1), true},
+		{"uint256", bigPow(2, 256, 0), false},
+		{"int24", bigPow(2, 23, -1), true},
+		{"int24", bigPow(2, 23, 0), false},
+		{"int24", new(big.Int).Neg(bigPow(2, 23, 0)), true},
+		{"int24", new(big.Int).Neg(bigPow(2, 23, 1)), false},
+		{"int256", bigPow(2, 255, -1), true},
+		{"int256", bigPow(2, 255, 0), false},
+		{"int256", new(big.Int).Neg(bigPow(2, 255, 0)), true},
+		{"int256", new(big.Int).Neg(bigPow(2, 255, 1)), false},
+	} {
+		typ, err := NewType(test.typ, "", nil)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		_, err = typ.pack(reflect.ValueOf(test.value))
+		if test.ok && err != nil {
+			t.Errorf("test %d: %s %v: unexpected error %v", i, test.typ, test.value, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("test %d: %s %v: expected overflow error", i, test.typ, test.value)
+		}
+	}
+}
+
+func TestPackNilPointers(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	packed, err := uint256Type.pack(reflect.ValueOf((*big.Int)(nil)))
+	if err != nil {
+		t.Fatalf("nil *big.Int: %v", err)
+	}
+	if !reflect.DeepEqual(packed, make([]byte, 32)) {
+		t.Errorf("nil *big.Int: have %x, want zero word", packed)
+	}
+
+	tupleType, _ := NewType("tuple", "", []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "bool"}})
+	type tuple struct {
+		A *big.Int
+		B bool
+	}
+	packed, err = tupleType.pack(reflect.ValueOf((*tuple)(nil)))
+	if err != nil {
+		t.Fatalf("nil tuple pointer: %v", err)
+	}
+	if !reflect.DeepEqual(packed, make([]byte, 64)) {
+		t.Errorf("nil tuple pointer: have %x, want two zero words", packed)
+	}
+	args := Arguments{{Name: "t", Type: tupleType}}
+	if _, err := args.Pack((*tuple)(nil)); err != nil {
+		t.Errorf("nil tuple argument: %v", err)
+	}
+
+	stringType, _ := NewType("string", "", nil)
+	if _, err := stringType.pack(reflect.ValueOf((*string)(nil))); err == nil {
+		t.Error("nil *string: expected error")
+	}
+}
+
+func TestPackNilSliceElement(t *testing.T) {
+	sliceType, _ := NewType("uint256[]", "", nil)
+	args := Arguments{{Name: "amounts", Type: sliceType}}
+	_, err := args.Pack([]*big.Int{big.NewInt(1), big.NewInt(2), nil})
+	if err == nil || err.Error() != "abi: element 2 of uint256[] is nil" {
+		t.Fatalf("have %v, want nil element error", err)
+	}
+	arrayType, _ := NewType("uint256[2]", "", nil)
+	if _, err := arrayType.pack(reflect.ValueOf([2]*big.Int{nil, big.NewInt(1)})); err == nil || err.Error() != "abi: element 0 of uint256[2] is nil" {
+		t.Fatalf("have %v, want nil element error", err)
+	}
+}
+
+func TestPackValidated(t *testing.T) {
+	uint8Type, _ := NewType("uint8", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	sliceType, _ := NewType("uint256[]", "", nil)
+	args := Arguments{{Name: "small", Type: uint8Type}, {Name: "name", Type: stringType}, {Name: "values", Type: sliceType}}
+
+	packed, err := args.PackValidated(uint8(1), "sero", []*big.Int{big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := args.Pack(uint8(1), "sero", []*big.Int{big.NewInt(1)}); !reflect.DeepEqual(packed, want) {
+		t.Errorf("have %x, want %x", packed, want)
+	}
+
+	tests := []struct {
+		values []interface{}
+		err    string
+	}{
+		{[]interface{}{uint8(1), "sero"}, "argument count mismatch: 2 for 3"},
+		{[]interface{}{uint16(256), "sero", []*big.Int{}}, "abi: argument 0 (small uint8): abi: value 256 overflows uint8"},
+		{[]interface{}{uint8(1), 5, []*big.Int{}}, "abi: argument 1 (name string): abi: cannot use int as type string as argument"},
+		{[]interface{}{uint8(1), "sero", []*big.Int{big.NewInt(-1)}}, "abi: argument 2 (values uint256[]): element 0: abi: value -1 overflows uint256"},
+		{[]interface{}{uint8(1), nil, []*big.Int{}}, "abi: argument 1 (name string): abi: missing value for string"},
+	}
+	for i, test := range tests {
+		if _, err := args.PackValidated(test.values...); err == nil || err.Error() != test.err {
+			t.Errorf("test %d: have error %v, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestTailSize(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	bytesType, _ := NewType("bytes", "", nil)
+	sliceType, _ := NewType("uint256[]", "", nil)
+	arrayType, _ := NewType("uint256[3]", "", nil)
+	args := Arguments{
+		{Name: "a", Type: uint256Type},
+		{Name: "b", Type: stringType},
+		{Name: "c", Type: arrayType},
+		{Name: "d", Type: bytesType},
+		{Name: "e", Type: sliceType},
+	}
+	values := []interface{}{
+		big.NewInt(1),
+		"a string longer than thirty two bytes",
+		[3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		[]byte{},
+		[]*big.Int{big.NewInt(4), big.NewInt(5)},
+	}
+	tail, err := args.TailSize(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// string: length + 2 words, bytes: length only, slice: length + 2 words
+	if tail != 32*3+32+32*3 {
+		t.Errorf("have tail size %d, want %d", tail, 32*7)
+	}
+	packed, err := args.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.headSize()+tail != len(packed) {
+		t.Errorf("head %d + tail %d != packed length %d", args.headSize(), tail, len(packed))
+	}
+	if _, err := args.TailSize(values[:2]...); err == nil || err.Error() != "argument count mismatch: 2 for 5" {
+		t.Errorf("have error %v, want count mismatch", err)
+	}
+}
+
+func TestPackAddressForm(t *testing.T) {
+	var pkr common.Address
+	for i := range pkr {
+		pkr[i] = byte(i + 1)
+	}
+	caddr := common.ContractAddress{0xaa, 0xbb}
+
+	packed, err := PackAddressForm(pkr, AddressFormPKr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrType, _ := NewType("address", "", nil)
+	if want, _ := addrType.pack(reflect.ValueOf(pkr)); !bytes.Equal(packed, want) {
+		t.Errorf("PKr form: have %x, want %x", packed, want)
+	}
+
+	packed, err = PackAddressForm(caddr[:], AddressFormContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.LeftPadBytes(caddr[:], 32); !bytes.Equal(packed, want) {
+		t.Errorf("contract form: have %x, want %x", packed, want)
+	}
+
+	args := Arguments{{Name: "to", Type: addrType, Encoder: func(v interface{}) ([]byte, error) {
+		return PackAddressForm(v, AddressFormContract)
+	}}}
+	if packed, err := args.Pack(&caddr); err != nil || !bytes.Equal(packed, common.LeftPadBytes(caddr[:], 32)) {
+		t.Errorf("contract form encoder: have %x %v", packed, err)
+	}
+
+	if _, err := PackAddressForm(caddr, AddressFormPKr); err == nil {
+		t.Error("expected error for short PKr")
+	}
+	if _, err := PackAddressForm(pkr, AddressFormContract); err == nil {
+		t.Error("expected error for long contract address")
+	}
+	if _, err := PackAddressForm("0x01", AddressFormContract); err == nil {
+		t.Error("expected error for string address")
+	}
+}
+
+func TestPackTokenMap(t *testing.T) {
+	m := map[c_type.Uint256]utils.U256{
+		utils.CurrencyToUint256Unchecked("SERO"): utils.NewU256(3),
+		utils.CurrencyToUint256Unchecked("ABC"):  utils.NewU256(1),
+		utils.CurrencyToUint256Unchecked("XYZ"):  utils.NewU256(2),
+	}
+	currencies, amounts := PackTokenMap(m)
+	if len(currencies) != 3 || len(amounts) != 3 {
+		t.Fatalf("have %d currencies and %d amounts, want 3", len(currencies), len(amounts))
+	}
+	// currencies are left padded, so they order by length first
+	for i, want := range []string{"ABC", "XYZ", "SERO"} {
+		if have := utils.Uint256ToCurrency(&currencies[i]); have != want {
+			t.Errorf("currency %d: have %s, want %s", i, have, want)
+		}
+		if amount := m[currencies[i]]; amounts[i].Cmp(amount.ToIntRef()) != 0 {
+			t.Errorf("amount %d: have %v, want %v", i, amounts[i], amount.ToIntRef())
+		}
+	}
+
+	currencyType, _ := NewType("bytes32[]", "", nil)
+	amountType, _ := NewType("uint256[]", "", nil)
+	args := Arguments{{Name: "currencies", Type: currencyType}, {Name: "amounts", Type: amountType}}
+	first, err := args.Pack(currencies, amounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if again, _ := args.Pack(PackTokenMap(m)); !bytes.Equal(first, again) {
+			t.Fatal("encoding is not reproducible")
+		}
+	}
+}
+
+func TestPackDynamicTupleArgument(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	tupleType, err := NewType("tuple", "", []ArgumentMarshaling{{Name: "memo", Type: "string"}, {Name: "data", Type: "bytes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := Arguments{{Name: "n", Type: uint256Type}, {Name: "t", Type: tupleType}}
+
+	type memo struct {
+		Memo string
+		Data []byte
+	}
+	packed, err := args.Pack(big.NewInt(1), memo{"abc", []byte{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000040" +
+		"0000000000000000000000000000000000000000000000000000000000000040" +
+		"0000000000000000000000000000000000000000000000000000000000000080" +
+		"0000000000000000000000000000000000000000000000000000000000000003" +
+		"6162630000000000000000000000000000000000000000000000000000000000" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"0102000000000000000000000000000000000000000000000000000000000000")
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("packed mismatch\nhave %x\nwant %x", packed, want)
+	}
+	var out struct {
+		N *big.Int
+		T memo
+	}
+	if err := args.Unpack(&out, packed); err != nil {
+		t.Fatal(err)
+	}
+	if out.N.Int64() != 1 || out.T.Memo != "abc" || !bytes.Equal(out.T.Data, []byte{1, 2}) {
+		t.Errorf("round trip mismatch: have %v %+v", out.N, out.T)
+	}
+	if size, err := args.TailSize(big.NewInt(1), memo{"abc", []byte{1, 2}}); err != nil || 64+size != len(packed) {
+		t.Errorf("tail size: have %d (%v), want %d", size, err, len(packed)-64)
+	}
+}
+
+func TestPackDynamicArrayArgument(t *testing.T) {
+	stringsType, _ := NewType("string[2]", "", nil)
+	uint256Type, _ := NewType("uint256", "", nil)
+	args := Arguments{{Name: "s", Type: stringsType}, {Name: "n", Type: uint256Type}}
+
+	packed, err := args.Pack([2]string{"a", "b"}, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		S [2]string
+		N *big.Int
+	}
+	if err := args.Unpack(&out, packed); err != nil {
+		t.Fatal(err)
+	}
+	if out.S != [2]string{"a", "b"} || out.N.Int64() != 7 {
+		t.Errorf("round trip mismatch: have %v %v", out.S, out.N)
+	}
+}
+
+func TestPackIntCoercion(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	int256Type, _ := NewType("int256", "", nil)
+	uint64Type, _ := NewType("uint64", "", nil)
+	int8Type, _ := NewType("int8", "", nil)
+
+	maxUint256 := new(big.Int).Set(MaxUint256)
+	for _, test := range []struct {
+		typ   Type
+		value interface{}
+		want  *big.Int
+	}{
+		{uint256Type, 42, big.NewInt(42)},
+		{uint256Type, int64(1) << 62, big.NewInt(1 << 62)},
+		{uint256Type, uint64(1<<64 - 1), new(big.Int).SetUint64(1<<64 - 1)},
+		{uint256Type, maxUint256, maxUint256},
+		{int256Type, -1, big.NewInt(-1)},
+		{int256Type, uint8(255), big.NewInt(255)},
+		{uint64Type, big.NewInt(7), big.NewInt(7)},
+		{uint64Type, 7, big.NewInt(7)},
+		{int8Type, -128, big.NewInt(-128)},
+		{int8Type, big.NewInt(127), big.NewInt(127)},
+	} {
+		packed, err := test.typ.pack(reflect.ValueOf(test.value))
+		if err != nil {
+			t.Errorf("%v from %T %v: %v", test.typ, test.value, test.value, err)
+			continue
+		}
+		if have := ReadInteger(test.typ, packed); fmt.Sprint(have) != test.want.String() {
+			t.Errorf("%v from %T %v: have %v", test.typ, test.value, test.value, have)
+		}
+	}
+	for _, test := range []struct {
+		typ   Type
+		value interface{}
+	}{
+		{uint256Type, -1},
+		{uint256Type, new(big.Int).Add(maxUint256, common.Big1)},
+		{uint64Type, big.NewInt(-1)},
+		{uint64Type, new(big.Int).Lsh(common.Big1, 64)},
+		{int8Type, 128},
+		{int8Type, big.NewInt(-129)},
+	} {
+		if _, err := test.typ.pack(reflect.ValueOf(test.value)); err == nil {
+			t.Errorf("%v from %T %v: expected overflow error", test.typ, test.value, test.value)
+		}
+	}
+	args := Arguments{{Name: "value", Type: uint256Type}}
+	if _, err := args.Pack(1000); err != nil {
+		t.Errorf("int argument: %v", err)
+	}
+}
+
+type testStatus uint8
+
+func TestPackNamedIntegers(t *testing.T) {
+	uint8Type, _ := NewType("uint8", "", nil)
+	uint256Type, _ := NewType("uint256", "", nil)
+	int8Type, _ := NewType("int8", "", nil)
+	want := common.LeftPadBytes([]byte{2}, 32)
+	for _, typ := range []Type{uint8Type, uint256Type, int8Type} {
+		packed, err := typ.pack(reflect.ValueOf(testStatus(2)))
+		if err != nil {
+			t.Fatalf("%v: %v", typ, err)
+		}
+		if !bytes.Equal(packed, want) {
+			t.Errorf("%v: have %x, want %x", typ, packed, want)
+		}
+	}
+	if _, err := int8Type.pack(reflect.ValueOf(testStatus(200))); err == nil {
+		t.Error("expected overflow packing 200 as int8")
+	}
+	args := Arguments{{Name: "status", Type: uint8Type}, {Name: "wide", Type: uint256Type}}
+	if _, err := args.PackValidated(testStatus(1), testStatus(1)); err != nil {
+		t.Error(err)
+	}
+
+	sliceType, _ := NewType("uint256[]", "", nil)
+	packed, err := sliceType.pack(reflect.ValueOf([]testStatus{1, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := sliceType.pack(reflect.ValueOf([]*big.Int{big.NewInt(1), big.NewInt(2)})); !bytes.Equal(packed, want) {
+		t.Errorf("slice: have %x, want %x", packed, want)
+	}
+	int8SliceType, _ := NewType("int8[]", "", nil)
+	if _, err := int8SliceType.pack(reflect.ValueOf([]testStatus{1, 200})); err == nil {
+		t.Error("expected overflow packing 200 as an int8 element")
+	}
+}
+
+func TestPackFixedBytesFromSlice(t *testing.T) {
+	for _, size := range []int{1, 20, 32} {
+		typ, _ := NewType(fmt.Sprintf("bytes%d", size), "", nil)
+		slice := make([]byte, size)
+		for i := range slice {
+			slice[i] = byte(i + 1)
+		}
+		array := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0)))).Elem()
+		reflect.Copy(array, reflect.ValueOf(slice))
+
+		packed, err := typ.pack(reflect.ValueOf(slice))
+		if err != nil {
+			t.Fatalf("bytes%d: %v", size, err)
+		}
+		if want, _ := typ.pack(array); !bytes.Equal(packed, want) {
+			t.Errorf("bytes%d: have %x, want %x", size, packed, want)
+		}
+		if _, err := typ.pack(reflect.ValueOf(make([]byte, size+1))); err == nil {
+			t.Errorf("bytes%d: expected error for %d bytes", size, size+1)
+		}
+		if _, err := typ.pack(reflect.ValueOf(slice[1:])); err == nil {
+			t.Errorf("bytes%d: expected error for %d bytes", size, size-1)
+		}
+	}
+
+	sliceType, _ := NewType("bytes32[]", "", nil)
+	if _, err := sliceType.pack(reflect.ValueOf([][]byte{make([]byte, 32), make([]byte, 32)})); err != nil {
+		t.Errorf("bytes32[]: %v", err)
+	}
+	if _, err := sliceType.pack(reflect.ValueOf([][]byte{make([]byte, 32), make([]byte, 31)})); err == nil {
+		t.Error("bytes32[]: expected error for a 31 byte element")
+	}
+}
+
+func TestPackNestedDynamicArrays(t *testing.T) {
+	typ, _ := NewType("uint256[][]", "", nil)
+	args := Arguments{{Type: typ}}
+	in := [][]*big.Int{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3)}, {}}
+	packed, err := args.Pack(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for _, word := range []int64{
+		0x20,              // offset of the outer array
+		3,                 // outer length
+		0x60, 0xc0, 0x100, // element offsets, relative to the first of them
+		2, 1, 2, // first element
+		1, 3, // second element
+		0, // third element
+	} {
+		want = append(want, common.LeftPadBytes(big.NewInt(word).Bytes(), 32)...)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("have %x\nwant %x", packed, want)
+	}
+	values, err := args.UnpackValues(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values[0], in) {
+		t.Errorf("round trip: have %v, want %v", values[0], in)
+	}
+
+	// a static array of dynamic arrays is a dynamic type with no length word
+	typ, _ = NewType("uint8[][2]", "", nil)
+	args = Arguments{{Type: typ}}
+	packed, err = args.Pack([2][]uint8{{7}, {8, 9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, err = args.UnpackValues(packed); err != nil || !reflect.DeepEqual(values[0], [2][]uint8{{7}, {8, 9}}) {
+		t.Errorf("uint8[][2] round trip: have %v %v", values, err)
+	}
+}
+
+func TestPackBoolArrays(t *testing.T) {
+	word := func(b bool) []byte {
+		if b {
+			return common.LeftPadBytes([]byte{1}, 32)
+		}
+		return make([]byte, 32)
+	}
+	arrayType, _ := NewType("bool[3]", "", nil)
+	sliceType, _ := NewType("bool[]", "", nil)
+	args := Arguments{{Type: arrayType}, {Type: sliceType}}
+	array, slice := [3]bool{true, false, true}, []bool{false, true}
+
+	packed, err := args.Pack(array, slice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for _, b := range array {
+		want = append(want, word(b)...)
+	}
+	want = append(want, common.LeftPadBytes([]byte{0x80}, 32)...)
+	want = append(want, common.LeftPadBytes([]byte{2}, 32)...)
+	for _, b := range slice {
+		want = append(want, word(b)...)
+	}
+	if !bytes.Equal(packed, want) {
+		t.Fatalf("have %x\nwant %x", packed, want)
+	}
+	values, err := args.UnpackValues(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{array, slice}) {
+		t.Errorf("round trip: have %v, want %v %v", values, array, slice)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// PreparedArguments holds Arguments together with the layout information that
+// Pack and Unpack would otherwise recompute on every call. It is safe for
+// concurrent use. The zero value holds no arguments; values made otherwise
+// than by Prepare work but don't cache the struct field mappings.
+type PreparedArguments struct {
+	arguments  Arguments
+	nonIndexed Arguments
+	headSize   int   // size of the packed heads, i.e. the start of the tail
+	offsets    []int // head offset of every non-indexed argument
+
+	fields *fieldCache
+}
+
+// fieldCache caches the argument name to struct field mapping per struct type.
+type fieldCache struct {
+	lock   sync.RWMutex
+	fields map[reflect.Type]map[string]string
+}
+
+// Prepare precomputes the layout of the arguments for repeated packing and
+// unpacking.
+func (arguments Arguments) Prepare() PreparedArguments {
+	nonIndexed := arguments.NonIndexed()
+	return PreparedArguments{
+		arguments:  arguments,
+		nonIndexed: nonIndexed,
+		headSize:   arguments.headSize(),
+		offsets:    nonIndexed.headOffsets(),
+		fields:     &fieldCache{fields: make(map[reflect.Type]map[string]string)},
+	}
+}
+
+// Arguments returns the arguments the layout was prepared for.
+func (p PreparedArguments) Arguments() Arguments {
+	return p.arguments
+}
+
+// Pack performs the operation Go format -> Hexdata, see Arguments.Pack.
+func (p PreparedArguments) Pack(args ...interface{}) ([]byte, error) {
+	return p.arguments.pack(p.headSize, args)
+}
+
+// UnpackValues unpacks the data into a list of values, see Arguments.UnpackValues.
+func (p PreparedArguments) UnpackValues(data []byte) ([]interface{}, error) {
+	return unpackValues(p.nonIndexed, p.offsets, data)
+}
+
+// Unpack performs the operation hexdata -> Go format, see Arguments.Unpack.
+func (p PreparedArguments) Unpack(v interface{}, data []byte) error {
+	if reflect.Ptr != reflect.ValueOf(v).Kind() {
+		return fmt.Errorf("abi: Unpack(non-pointer %T)", v)
+	}
+	marshalledValues, err := p.UnpackValues(data)
+	if err != nil {
+		return err
+	}
+	if len(marshalledValues) == 0 {
+		return fmt.Errorf("abi: Unpack(no-values unmarshalled %T)", v)
+	}
+	if settable, ok := v.(ABISettable); ok {
+		return p.nonIndexed.unpackIntoSetter(settable, marshalledValues)
+	}
+	if !p.arguments.isTuple() {
+		return p.arguments.unpackAtomic(v, marshalledValues[0])
+	}
+	value := reflect.ValueOf(v).Elem()
+	if value.Kind() != reflect.Struct {
+		return p.arguments.unpackTuple(v, marshalledValues)
+	}
+	abi2struct, err := p.structFields(value)
+	if err != nil {
+		return err
+	}
+	for i, arg := range p.nonIndexed {
+		field := value.FieldByName(abi2struct[arg.Name])
+		if !field.IsValid() {
+			return unpackErrorf(ErrFieldNotFound, "abi: field %s can't be found in the given value", arg.Name)
+		}
+		if err := set(field, reflect.ValueOf(marshalledValues[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structFields returns the cached argument to field mapping for the struct
+// type of value, resolving it on first use. Without a cache, as for the zero
+// value, it is resolved every time.
+func (p PreparedArguments) structFields(value reflect.Value) (map[string]string, error) {
+	if p.fields != nil {
+		p.fields.lock.RLock()
+		abi2struct, ok := p.fields.fields[value.Type()]
+		p.fields.lock.RUnlock()
+		if ok {
+			return abi2struct, nil
+		}
+	}
+	argNames := make([]string, len(p.nonIndexed))
+	for i, arg := range p.nonIndexed {
+		argNames[i] = arg.Name
+	}
+	abi2struct, err := mapArgNamesToStructFields(argNames, value)
+	if err != nil || p.fields == nil {
+		return abi2struct, err
+	}
+	p.fields.lock.Lock()
+	p.fields.fields[value.Type()] = abi2struct
+	p.fields.lock.Unlock()
+	return abi2struct, nil
+}
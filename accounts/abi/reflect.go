@@ -22,17 +22,39 @@ import (
 	"math/big"
 	"reflect"
 	"strings"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common/math"
+	"github.com/sero-cash/go-sero/zero/utils"
 )
 
 // indirect recursively dereferences the value until it either gets the value
 // or finds a big.Int
 func indirect(v reflect.Value) reflect.Value {
-	if v.Kind() == reflect.Ptr && v.Elem().Type() != reflect.TypeOf(big.Int{}) {
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type() != reflect.TypeOf(big.Int{}) {
 		return indirect(v.Elem())
 	}
 	return v
 }
 
+// seroValue converts the SERO specific number types to the *big.Int the
+// packer expects for integer types: utils.U256 values and c_type.Uint256
+// words, such as the currency of a utils.Asset.
+func seroValue(t Type, v reflect.Value) reflect.Value {
+	if t.T != IntTy && t.T != UintTy || !v.IsValid() {
+		return v
+	}
+	switch v.Type() {
+	case reflect.TypeOf(utils.U256{}):
+		n := v.Interface().(utils.U256)
+		return reflect.ValueOf(new(big.Int).Set((*big.Int)(&n)))
+	case reflect.TypeOf(c_type.Uint256{}):
+		u := v.Interface().(c_type.Uint256)
+		return reflect.ValueOf(new(big.Int).SetBytes(u[:]))
+	}
+	return v
+}
+
 // reflectIntType returns the reflect using the given size and
 // unsignedness.
 func reflectIntType(unsigned bool, size int) reflect.Type {
@@ -78,10 +100,16 @@ func set(dst, src reflect.Value) error {
 	switch {
 	case dstType.Kind() == reflect.Interface && dst.Elem().IsValid():
 		return set(dst.Elem(), src)
-	case dstType.Kind() == reflect.Ptr && dstType.Elem() != reflect.TypeOf(big.Int{}):
-		return set(dst.Elem(), src)
 	case srcType.AssignableTo(dstType) && dst.CanSet():
 		dst.Set(src)
+	case srcType == reflect.TypeOf(new(big.Int)) && dstType == reflect.TypeOf(utils.U256{}) && dst.CanSet():
+		dst.Set(reflect.ValueOf(utils.U256(*new(big.Int).Set(src.Interface().(*big.Int)))))
+	case srcType == reflect.TypeOf(new(big.Int)) && dstType == reflect.TypeOf(c_type.Uint256{}) && dst.CanSet():
+		var u c_type.Uint256
+		copy(u[:], math.PaddedBigBytes(src.Interface().(*big.Int), 32))
+		dst.Set(reflect.ValueOf(u))
+	case dstType.Kind() == reflect.Ptr && dstType.Elem() != reflect.TypeOf(big.Int{}):
+		return set(dst.Elem(), src)
 	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice && dst.CanSet():
 		return setSlice(dst, src)
 	case dstType.Kind() == reflect.Array:
@@ -89,7 +117,7 @@ func set(dst, src reflect.Value) error {
 	case dstType.Kind() == reflect.Struct:
 		return setStruct(dst, src)
 	default:
-		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+		return unpackErrorf(ErrTypeMismatch, "abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
 	}
 	return nil
 }
@@ -99,12 +127,19 @@ func set(dst, src reflect.Value) error {
 // setSlice ignores if we cannot copy all of src' elements.
 func setSlice(dst, src reflect.Value) error {
 	slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
-	for i := 0; i < src.Len(); i++ {
-		if src.Index(i).Kind() == reflect.Struct {
-			if err := set(slice.Index(i), src.Index(i)); err != nil {
+	if src.Type().Elem().Kind() == reflect.Struct && dst.Type().Elem().Kind() == reflect.Struct {
+		// resolve the field mapping once for all the elements
+		fields, err := structFieldIndices(dst.Type().Elem(), src.Type().Elem())
+		if err != nil {
+			return err
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := setStructFields(slice.Index(i), src.Index(i), fields); err != nil {
 				return err
 			}
-		} else {
+		}
+	} else {
+		for i := 0; i < src.Len(); i++ {
 			// e.g. [][32]uint8 to []common.Hash
 			if err := set(slice.Index(i), src.Index(i)); err != nil {
 				return err
@@ -137,11 +172,21 @@ func setArray(dst, src reflect.Value) error {
 }
 
 func setStruct(dst, src reflect.Value) error {
-	for i := 0; i < src.NumField(); i++ {
+	fields, err := structFieldIndices(dst.Type(), src.Type())
+	if err != nil {
+		return err
+	}
+	return setStructFields(dst, src, fields)
+}
+
+// setStructFields copies every field of src into the dst field at the matching
+// position of fields.
+func setStructFields(dst, src reflect.Value, fields []int) error {
+	for i, j := range fields {
 		srcField := src.Field(i)
-		dstField := dst.Field(i)
+		dstField := dst.Field(j)
 		if !dstField.IsValid() || !srcField.IsValid() {
-			return fmt.Errorf("Could not find src field: %v value: %v in destination", srcField.Type().Name(), srcField)
+			return unpackErrorf(ErrFieldNotFound, "Could not find src field: %v value: %v in destination", srcField.Type().Name(), srcField)
 		}
 		if err := set(dstField, srcField); err != nil {
 			return err
@@ -150,6 +195,40 @@ func setStruct(dst, src reflect.Value) error {
 	return nil
 }
 
+// structFieldIndices maps each field of the src struct type to the index of
+// the dst field it is assigned to. Fields are matched by name, then by the abi
+// tag against the raw tuple component name, and finally by position. Two src
+// fields mapped to the same dst field are an error.
+func structFieldIndices(dst, src reflect.Type) ([]int, error) {
+	fields := make([]int, src.NumField())
+	taken := make([]bool, dst.NumField())
+	for i := 0; i < src.NumField(); i++ {
+		fields[i] = -1
+		srcField := src.Field(i)
+		if f, ok := dst.FieldByName(srcField.Name); ok && len(f.Index) == 1 {
+			fields[i] = f.Index[0]
+		} else if name := srcField.Tag.Get("json"); name != "" {
+			for j := 0; j < dst.NumField(); j++ {
+				if dst.Field(j).Tag.Get("abi") == name {
+					fields[i] = j
+					break
+				}
+			}
+		}
+		if fields[i] == -1 {
+			if i >= dst.NumField() {
+				return nil, unpackErrorf(ErrFieldNotFound, "Could not find src field: %v in destination %v", srcField.Name, dst)
+			}
+			fields[i] = i
+		}
+		if taken[fields[i]] {
+			return nil, unpackErrorf(ErrTupleMismatch, "abi: src field %v maps to destination field %v, which is already taken", srcField.Name, dst.Field(fields[i]).Name)
+		}
+		taken[fields[i]] = true
+	}
+	return fields, nil
+}
+
 // mapArgNamesToStructFields maps a slice of argument names to struct fields.
 // first round: for each Exportable field that contains a `abi:""` tag
 //   and this field name exists in the given argument name list, pair them together.
@@ -0,0 +1,114 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseSignature parses a canonical signature such as
+// "transfer(address,uint256)" into the method name and its arguments. As a
+// signature carries no names, the arguments and the components of tuples,
+// written as "(address,uint256)[2]", are named arg0, arg1, ...
+func ParseSignature(sig string) (name string, args Arguments, err error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return "", nil, fmt.Errorf("abi: invalid signature %q", sig)
+	}
+	name = sig[:open]
+	components, err := parseSignatureTypes(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return "", nil, fmt.Errorf("abi: invalid signature %q: %v", sig, err)
+	}
+	args = make(Arguments, len(components))
+	for i, c := range components {
+		typ, err := NewType(c.Type, "", c.Components)
+		if err != nil {
+			return "", nil, err
+		}
+		args[i] = Argument{Name: c.Name, Type: typ}
+	}
+	return name, args, nil
+}
+
+// SelectorForSignature returns the 4 byte selector of a canonical signature
+// such as "transfer(address,uint256)", hashed the same way as a Method's.
+// Type aliases like uint are normalised before hashing.
+func SelectorForSignature(sig string) (selector [4]byte, err error) {
+	name, args, err := ParseSignature(sig)
+	if err != nil {
+		return selector, err
+	}
+	copy(selector[:], args.selector(name))
+	return selector, nil
+}
+
+// parseSignatureTypes splits a comma separated list of canonical types,
+// descending into tuples.
+func parseSignatureTypes(list string) ([]ArgumentMarshaling, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var (
+		types []ArgumentMarshaling
+		depth int
+		start int
+	)
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth < 0 {
+					return nil, fmt.Errorf("unbalanced parentheses")
+				}
+			}
+			if list[i] != ',' || depth > 0 {
+				continue
+			}
+		}
+		if depth != 0 {
+			return nil, fmt.Errorf("unbalanced parentheses")
+		}
+		typ, err := parseSignatureType(list[start:i], len(types))
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+		start = i + 1
+	}
+	return types, nil
+}
+
+// parseSignatureType parses a single canonical type, the index-th of its list.
+func parseSignatureType(typ string, index int) (ArgumentMarshaling, error) {
+	name := fmt.Sprintf("arg%d", index)
+	if typ == "" {
+		return ArgumentMarshaling{}, fmt.Errorf("empty type")
+	}
+	if typ[0] != '(' {
+		return ArgumentMarshaling{Name: name, Type: typ}, nil
+	}
+	end := strings.LastIndex(typ, ")")
+	components, err := parseSignatureTypes(typ[1:end])
+	if err != nil {
+		return ArgumentMarshaling{}, err
+	}
+	return ArgumentMarshaling{Name: name, Type: "tuple" + typ[end+1:], Components: components}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+func TestParseSignature(t *testing.T) {
+	for _, sig := range []string{
+		"transfer(address,uint256)",
+		"noop()",
+		"batch((address,uint256)[2],bytes)",
+		"nested(((uint8,string),bool)[],uint256[3][])",
+	} {
+		name, args, err := ParseSignature(sig)
+		if err != nil {
+			t.Fatalf("%s: %v", sig, err)
+		}
+		if method := NewMethod(name, name, Function, "", false, false, args, nil); method.Sig != sig {
+			t.Errorf("have %q, want %q", method.Sig, sig)
+		}
+		for i, arg := range args {
+			if want := "arg" + string(rune('0'+i)); arg.Name != want {
+				t.Errorf("%s: argument %d named %q, want %q", sig, i, arg.Name, want)
+			}
+		}
+	}
+
+	_, args, err := ParseSignature("transfer(address,uint256)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := args[1:].Pack(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, err := args[1:].UnpackValues(data); err != nil || values[0].(*big.Int).Int64() != 42 {
+		t.Errorf("have %v %v, want 42", values, err)
+	}
+
+	for _, sig := range []string{"", "transfer", "(uint256)", "f(uint256", "f((uint256)", "f(uint256))", "f(uint256,)", "f(foo)"} {
+		if _, _, err := ParseSignature(sig); err == nil {
+			t.Errorf("%q: expected error", sig)
+		}
+	}
+}
+
+func TestSelectorForSignature(t *testing.T) {
+	for sig, want := range map[string]string{
+		"transfer(address,uint256)": "a9059cbb",
+		"transfer(address,uint)":    "a9059cbb",
+		"balanceOf(address)":        "70a08231",
+	} {
+		selector, err := SelectorForSignature(sig)
+		if err != nil {
+			t.Fatalf("%s: %v", sig, err)
+		}
+		if common.Bytes2Hex(selector[:]) != want {
+			t.Errorf("%s: have %x, want %s", sig, selector, want)
+		}
+	}
+	for _, sig := range []string{"transfer(address,uint256", "transfer((address,uint256)", "transfer(address,uint256))", "transfer(foo)", "(uint256)"} {
+		if _, err := SelectorForSignature(sig); err == nil {
+			t.Errorf("%s: expected error", sig)
+		}
+	}
+}
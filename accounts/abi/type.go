@@ -18,6 +18,7 @@ package abi
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -46,7 +47,8 @@ const (
 	FunctionTy
 )
 
-// Type is the reflection of the supported argument type
+// Type is the reflection of the supported argument type.
+// A Type is read-only once NewType returns it.
 type Type struct {
 	Elem *Type
 	Size int
@@ -66,10 +68,43 @@ var (
 	typeRegex = regexp.MustCompile("([a-zA-Z]+)(([0-9]+)(x([0-9]+))?)?")
 )
 
+// MaxArrayLength is the largest number of elements NewType accepts for a
+// fixed size array, counting all the dimensions of nested arrays. It guards
+// tools parsing untrusted ABIs against absurd declarations such as
+// uint256[4294967295]. Set it before parsing, it is not synchronised.
+var MaxArrayLength = 1 << 16
+
+// SeroAddressTypeString returns the canonical type name of SERO addresses in
+// signatures. Addresses are passed as PKrs but contracts are compiled against
+// the plain solidity address type, so selectors and topics must hash
+// "address" to match the deployed contracts. All signature building goes
+// through Type.String, which uses this name for address types.
+func SeroAddressTypeString() string {
+	return "address"
+}
+
 // String implements Stringer
 
 // NewType creates a new reflection type of abi type given in t.
-func NewType(t string, internalType string, components []ArgumentMarshaling) (typ Type, err error) {
+//
+// Parsed types are cached and the returned value shares its element and
+// component types with every other caller, so a Type must be treated as
+// read-only.
+func NewType(t string, internalType string, components []ArgumentMarshaling) (Type, error) {
+	key := typeCacheKey(t, internalType, components)
+	if typ, ok := cachedType(key); ok {
+		return typ, nil
+	}
+	typ, err := newType(t, internalType, components)
+	if err != nil {
+		return Type{}, err
+	}
+	cacheType(key, typ)
+	return typ, nil
+}
+
+// newType parses the abi type given in t.
+func newType(t string, internalType string, components []ArgumentMarshaling) (typ Type, err error) {
 	// check that array brackets are equal if they exist
 	if strings.Count(t, "[") != strings.Count(t, "]") {
 		return Type{}, fmt.Errorf("invalid arg type in abi")
@@ -109,6 +144,15 @@ func NewType(t string, internalType string, components []ArgumentMarshaling) (ty
 			if err != nil {
 				return Type{}, fmt.Errorf("abi: error parsing variable size: %v", err)
 			}
+			// nested static arrays are laid out inline, so cap the total
+			// element count rather than each dimension alone
+			elems := typ.Size
+			for elem := &embeddedType; elem.T == ArrayTy; elem = elem.Elem {
+				elems *= elem.Size
+			}
+			if typ.Size > MaxArrayLength || elems > MaxArrayLength {
+				return Type{}, fmt.Errorf("abi: array type %v exceeds the maximum of %d elements", t, MaxArrayLength)
+			}
 			typ.stringKind = embeddedType.stringKind + sliced
 		} else {
 			return Type{}, fmt.Errorf("invalid formatting of array type")
@@ -132,9 +176,10 @@ func NewType(t string, internalType string, components []ArgumentMarshaling) (ty
 		}
 	} else {
 		if parsedType[0] == "uint" || parsedType[0] == "int" {
-			// this should fail because it means that there's something wrong with
-			// the abi type (the compiler should always format it to the size...always)
-			return Type{}, fmt.Errorf("unsupported arg type: %s", t)
+			// bare int and uint are aliases for int256 and uint256, the canonical
+			// form is used for the signature.
+			varSize = 256
+			typ.stringKind = parsedType[0] + "256"
 		}
 	}
 	// varType is the parsed abi type
@@ -150,6 +195,7 @@ func NewType(t string, internalType string, components []ArgumentMarshaling) (ty
 	case "address":
 		typ.Size = 20
 		typ.T = AddressTy
+		typ.stringKind = SeroAddressTypeString()
 	case "string":
 		typ.T = StringTy
 	case "bytes":
@@ -218,6 +264,14 @@ func NewType(t string, internalType string, components []ArgumentMarshaling) (ty
 	return
 }
 
+// GetType returns the Go type values of t are unpacked into: *big.Int or a
+// sized integer, common.ContractAddress for addresses, slices and arrays of
+// the element type and a generated struct for tuples. Mappings registered with
+// RegisterTypeMapping are not taken into account.
+func (t Type) GetType() reflect.Type {
+	return t.getType()
+}
+
 func (t Type) getType() reflect.Type {
 	switch t.T {
 	case IntTy:
@@ -253,6 +307,10 @@ func (t Type) getType() reflect.Type {
 	}
 }
 
+// String returns the canonical form of the type as used in signatures, e.g.
+// uint256 for uint, address[] or (uint256,bytes)[2]. Types other than tuples
+// parse back into the same type with NewType; tuples are written out as their
+// component types, so they parse back from "tuple" and their components.
 func (t Type) String() (out string) {
 	return t.stringKind
 }
@@ -273,13 +331,26 @@ func overloadedArgName(rawName string, names map[string]string) (string, error)
 
 func (t Type) pack(v reflect.Value) ([]byte, error) {
 	// dereference pointer first if it's a pointer
-	v = indirect(v)
+	v, err := t.zeroNil(seroValue(t, indirect(v)))
+	if err != nil {
+		return nil, err
+	}
+	if v, err = coerceInt(t, v); err != nil {
+		return nil, err
+	}
 	if err := typeCheck(t, v); err != nil {
 		return nil, err
 	}
 
 	switch t.T {
 	case SliceTy, ArrayTy:
+		// nil elements are rejected up front rather than zeroed like a nil
+		// argument, as they usually mean a batch was only partly filled
+		for i := 0; i < v.Len(); i++ {
+			if elem := v.Index(i); (elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface) && elem.IsNil() {
+				return nil, fmt.Errorf("abi: element %d of %v is nil", i, t)
+			}
+		}
 		var ret []byte
 
 		if t.requiresLengthPrefix() {
@@ -322,34 +393,167 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		// Calculate prefix occupied size.
-		offset := 0
-		for _, elem := range t.TupleElems {
-			offset += getTypeSize(*elem)
-		}
-		var ret, tail []byte
-		for i, elem := range t.TupleElems {
+		fields := make([]reflect.Value, len(t.TupleElems))
+		for i := range t.TupleElems {
 			field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
 			if !field.IsValid() {
 				return nil, fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
 			}
-			val, err := elem.pack(field)
-			if err != nil {
+			fields[i] = field
+		}
+		return t.packTuple(fields)
+
+	default:
+		if t.T == IntTy || t.T == UintTy {
+			if err := checkIntRange(t, v); err != nil {
 				return nil, err
 			}
-			if isDynamicType(*elem) {
-				ret = append(ret, packNum(reflect.ValueOf(offset))...)
-				tail = append(tail, val...)
-				offset += len(val)
-			} else {
-				ret = append(ret, val...)
+		}
+		return packElement(t, v), nil
+	}
+}
+
+// validate checks that v can be packed as t without packing it, descending
+// into the elements of arrays, slices and tuples. Errors name the offending
+// element or field.
+func (t Type) validate(v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("abi: missing value for %v", t)
+	}
+	v, err := t.zeroNil(seroValue(t, indirect(v)))
+	if err != nil {
+		return err
+	}
+	if v, err = coerceInt(t, v); err != nil {
+		return err
+	}
+	if err := typeCheck(t, v); err != nil {
+		return err
+	}
+	switch t.T {
+	case SliceTy, ArrayTy:
+		for i := 0; i < v.Len(); i++ {
+			if err := t.Elem.validate(v.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
 			}
 		}
-		return append(ret, tail...), nil
+	case TupleTy:
+		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, v)
+		if err != nil {
+			return err
+		}
+		for i, elem := range t.TupleElems {
+			field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
+			if !field.IsValid() {
+				return fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
+			}
+			if err := elem.validate(field); err != nil {
+				return fmt.Errorf("field %s: %v", t.TupleRawNames[i], err)
+			}
+		}
+	case IntTy, UintTy:
+		return checkIntRange(t, v)
+	}
+	return nil
+}
+
+// zeroNil replaces a nil pointer with the zero value it stands for: zero for
+// integer types and the all-zero tuple for a pointer to a struct. Nil pointers
+// of any other type can't be packed and yield an error.
+func (t Type) zeroNil(v reflect.Value) (reflect.Value, error) {
+	if v.Kind() != reflect.Ptr || !v.IsNil() {
+		return v, nil
+	}
+	elem := v.Type().Elem()
+	switch {
+	case (t.T == IntTy || t.T == UintTy) && elem == reflect.TypeOf(big.Int{}):
+		return reflect.ValueOf(new(big.Int)), nil
+	case t.T == IntTy || t.T == UintTy:
+		return reflect.Zero(elem), nil
+	case t.T == TupleTy && elem.Kind() == reflect.Struct:
+		return reflect.Zero(elem), nil
+	}
+	return v, fmt.Errorf("abi: cannot pack nil %v as %v", v.Type(), t)
+}
+
+// packTuple packs the given values as the components of the tuple t.
+func (t Type) packTuple(fields []reflect.Value) ([]byte, error) {
+	// Calculate prefix occupied size.
+	offset := 0
+	for _, elem := range t.TupleElems {
+		offset += getTypeSize(*elem)
+	}
+	var ret, tail []byte
+	for i, elem := range t.TupleElems {
+		val, err := elem.pack(fields[i])
+		if err != nil {
+			return nil, err
+		}
+		if isDynamicType(*elem) {
+			ret = append(ret, packNum(reflect.ValueOf(offset))...)
+			tail = append(tail, val...)
+			offset += len(val)
+		} else {
+			ret = append(ret, val...)
+		}
+	}
+	return append(ret, tail...), nil
+}
 
+// packInPlace returns the in-place encoding of v hashed into the topic of an
+// indexed argument: the elements of arrays and the fields of tuples are
+// concatenated without offsets or lengths, each padded to a whole word, and
+// a top level string or bytes value is its content without any padding.
+func (t Type) packInPlace(v reflect.Value, top bool) ([]byte, error) {
+	switch t.T {
+	case StringTy, BytesTy, SliceTy, ArrayTy, TupleTy:
 	default:
-		return packElement(t, v), nil
+		return t.pack(v)
 	}
+	v, err := t.zeroNil(seroValue(t, indirect(v)))
+	if err != nil {
+		return nil, err
+	}
+	if err := typeCheck(t, v); err != nil {
+		return nil, err
+	}
+	var ret []byte
+	switch t.T {
+	case StringTy, BytesTy:
+		if t.T == StringTy {
+			ret = []byte(v.String())
+		} else {
+			ret = v.Bytes()
+		}
+		if !top {
+			ret = common.RightPadBytes(ret, (len(ret)+31)/32*32)
+		}
+	case SliceTy, ArrayTy:
+		for i := 0; i < v.Len(); i++ {
+			elem, err := t.Elem.packInPlace(v.Index(i), false)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %v", i, err)
+			}
+			ret = append(ret, elem...)
+		}
+	case TupleTy:
+		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, v)
+		if err != nil {
+			return nil, err
+		}
+		for i, elem := range t.TupleElems {
+			field := v.FieldByName(fieldmap[t.TupleRawNames[i]])
+			if !field.IsValid() {
+				return nil, fmt.Errorf("field %s for tuple not found in the given struct", t.TupleRawNames[i])
+			}
+			enc, err := elem.packInPlace(field, false)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", t.TupleRawNames[i], err)
+			}
+			ret = append(ret, enc...)
+		}
+	}
+	return ret, nil
 }
 
 //func (t Type) pack(v reflect.Value) ([]byte, error) {
@@ -381,7 +585,9 @@ func (t Type) pack(v reflect.Value) ([]byte, error) {
 
 func (t Type) getAllAddress(v reflect.Value) (pkrs []c_type.PKr, err error) {
 	// dereference pointer first if it's a pointer
-	v = indirect(v)
+	if v, err = t.zeroNil(indirect(v)); err != nil {
+		return nil, err
+	}
 
 	if err := typeCheck(t, v); err != nil {
 		return nil, err
@@ -462,11 +668,9 @@ func isDynamicType(t Type) bool {
 // to store the location reference for actual value storage.
 func getTypeSize(t Type) int {
 	if t.T == ArrayTy && !isDynamicType(*t.Elem) {
-		// Recursively calculate type size if it is a nested array
-		if t.Elem.T == ArrayTy {
-			return t.Size * getTypeSize(*t.Elem)
-		}
-		return t.Size * 32
+		// Recursively calculate type size if it is a nested array or an
+		// array of static tuples
+		return t.Size * getTypeSize(*t.Elem)
 	} else if t.T == TupleTy && !isDynamicType(t) {
 		total := 0
 		for _, elem := range t.TupleElems {
@@ -0,0 +1,294 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+func TestBareIntTypes(t *testing.T) {
+	for _, test := range []struct {
+		bare, sized string
+		value       interface{}
+	}{
+		{"uint", "uint256", big.NewInt(42)},
+		{"int", "int256", big.NewInt(-42)},
+		{"uint[3]", "uint256[3]", [3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}},
+		{"int[]", "int256[]", []*big.Int{big.NewInt(-1), big.NewInt(2)}},
+	} {
+		bare, err := NewType(test.bare, "", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.bare, err)
+		}
+		sized, err := NewType(test.sized, "", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.sized, err)
+		}
+		if !reflect.DeepEqual(bare, sized) {
+			t.Errorf("%s: type mismatch, got %+v want %+v", test.bare, bare, sized)
+		}
+		if bare.String() != test.sized {
+			t.Errorf("%s: canonical form mismatch, got %s want %s", test.bare, bare.String(), test.sized)
+		}
+		bp, err := Arguments{{Type: bare}}.Pack(test.value)
+		if err != nil {
+			t.Fatalf("%s: %v", test.bare, err)
+		}
+		sp, err := Arguments{{Type: sized}}.Pack(test.value)
+		if err != nil {
+			t.Fatalf("%s: %v", test.sized, err)
+		}
+		if !bytes.Equal(bp, sp) {
+			t.Errorf("%s: packed mismatch, got %x want %x", test.bare, bp, sp)
+		}
+	}
+}
+
+func TestTupleRawName(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "a", Type: "uint256"}, {Name: "b", Type: "bytes"}}
+	typ, err := NewType("tuple", "struct Foo", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.TupleRawName != "Foo" {
+		t.Errorf("got struct name %q, want Foo", typ.TupleRawName)
+	}
+	typ, err = NewType("tuple[2]", "struct Lib.Foo[2]", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.Elem.TupleRawName != "LibFoo" {
+		t.Errorf("got struct name %q, want LibFoo", typ.Elem.TupleRawName)
+	}
+	typ, err = NewType("tuple", "", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.TupleRawName != "" {
+		t.Errorf("got struct name %q without internal type", typ.TupleRawName)
+	}
+}
+
+func TestSeroAddressTypeString(t *testing.T) {
+	if SeroAddressTypeString() != "address" {
+		t.Fatalf("have %q, want address", SeroAddressTypeString())
+	}
+	addr := SeroAddressTypeString()
+	for _, test := range []struct {
+		typ  string
+		want string
+	}{
+		{"address", addr},
+		{"address[]", addr + "[]"},
+		{"address[2][]", addr + "[2][]"},
+	} {
+		typ, err := NewType(test.typ, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ.String() != test.want {
+			t.Errorf("%s: have %q, want %q", test.typ, typ.String(), test.want)
+		}
+	}
+
+	addrType, _ := NewType("address", "", nil)
+	tupleType, _ := NewType("tuple", "", []ArgumentMarshaling{{Name: "to", Type: "address"}, {Name: "value", Type: "uint256"}})
+	inputs := Arguments{{Name: "to", Type: addrType}, {Name: "order", Type: tupleType}}
+	if sig, want := NewMethod("send", "send", Function, "", false, false, inputs, nil).Sig, "send("+addr+",("+addr+",uint256))"; sig != want {
+		t.Errorf("method signature: have %q, want %q", sig, want)
+	}
+	if sig, want := NewEvent("Sent", "Sent", false, inputs).Sig, "Sent("+addr+",("+addr+",uint256))"; sig != want {
+		t.Errorf("event signature: have %q, want %q", sig, want)
+	}
+}
+
+func TestMaxArrayLength(t *testing.T) {
+	defer func(max int) { MaxArrayLength = max }(MaxArrayLength)
+	MaxArrayLength = 16
+
+	for _, test := range []struct {
+		typ string
+		ok  bool
+	}{
+		{"uint256[16]", true},
+		{"uint256[17]", false},
+		{"uint256[4][4]", true},
+		{"uint256[4][5]", false},
+		{"uint256[17][]", false},
+		{"uint256[]", true},
+		{"uint256[4294967295]", false},
+	} {
+		_, err := NewType(test.typ, "", nil)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: have error %v, want ok %v", test.typ, err, test.ok)
+		}
+	}
+}
+
+func TestTypeGetType(t *testing.T) {
+	for _, test := range []struct {
+		typ  string
+		want reflect.Type
+	}{
+		{"uint256", reflect.TypeOf(new(big.Int))},
+		{"int32", reflect.TypeOf(int32(0))},
+		{"address", reflect.TypeOf(common.ContractAddress{})},
+		{"bytes", reflect.TypeOf([]byte{})},
+		{"bytes32[2]", reflect.TypeOf([2][32]byte{})},
+		{"string[]", reflect.TypeOf([]string{})},
+	} {
+		typ, err := NewType(test.typ, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ.GetType() != test.want {
+			t.Errorf("%s: have %v, want %v", test.typ, typ.GetType(), test.want)
+		}
+	}
+
+	tupleType, _ := NewType("tuple[]", "", []ArgumentMarshaling{{Name: "to", Type: "address"}, {Name: "value", Type: "uint256"}})
+	args := Arguments{{Name: "pairs", Type: tupleType}}
+	packed, err := args.Pack([]struct {
+		To    common.ContractAddress
+		Value *big.Int
+	}{{common.ContractAddress{1}, big.NewInt(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := args.UnpackValues(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.TypeOf(values[0]) != tupleType.GetType() {
+		t.Errorf("have unpacked %T, want %v", values[0], tupleType.GetType())
+	}
+	dst := reflect.New(tupleType.GetType())
+	if err := args.Unpack(dst.Interface(), packed); err != nil || dst.Elem().Len() != 1 {
+		t.Errorf("unpack into preallocated destination: %v", err)
+	}
+}
+
+func TestNewTypeCached(t *testing.T) {
+	components := []ArgumentMarshaling{{Name: "to", Type: "address"}, {Name: "memo", Type: "string"}}
+	a, err := NewType("tuple[]", "", components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewType("tuple[]", "", components)
+	if a.Elem != b.Elem {
+		t.Error("repeated type not shared")
+	}
+	renamed, _ := NewType("tuple[]", "", []ArgumentMarshaling{{Name: "to", Type: "address"}, {Name: "note", Type: "string"}})
+	if renamed.Elem == a.Elem || renamed.Elem.TupleRawNames[1] != "note" {
+		t.Error("types with different components share a cache entry")
+	}
+
+	defer func(max int) { MaxArrayLength = max }(MaxArrayLength)
+	if _, err := NewType("uint256[8]", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	MaxArrayLength = 4
+	if _, err := NewType("uint256[8]", "", nil); err == nil {
+		t.Error("cached type bypassed MaxArrayLength")
+	}
+}
+
+func BenchmarkABIUnmarshal(b *testing.B) {
+	var methods []string
+	for i := 0; i < 200; i++ {
+		methods = append(methods, fmt.Sprintf(`{"type":"function","name":"method%d","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"id","type":"bytes32"},{"name":"pairs","type":"tuple[]","components":[{"name":"currency","type":"bytes32"},{"name":"amount","type":"uint256"}]}],"outputs":[{"name":"ok","type":"bool"}]}`, i))
+	}
+	definition := "[" + strings.Join(methods, ",") + "]"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := JSON(strings.NewReader(definition)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTypeStringRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		typ  string
+		want string
+	}{
+		{"uint", "uint256"},
+		{"int8", "int8"},
+		{"bool", "bool"},
+		{"address", "address"},
+		{"string", "string"},
+		{"bytes", "bytes"},
+		{"bytes32", "bytes32"},
+		{"function", "function"},
+		{"uint[2]", "uint256[2]"},
+		{"bytes32[2][3]", "bytes32[2][3]"},
+		{"address[]", "address[]"},
+		{"string[][]", "string[][]"},
+		{"int[3][]", "int256[3][]"},
+	} {
+		typ, err := NewType(test.typ, "", nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.typ, err)
+		}
+		if typ.String() != test.want {
+			t.Errorf("%s: have %q, want %q", test.typ, typ.String(), test.want)
+		}
+		parsed, err := NewType(typ.String(), "", nil)
+		if err != nil {
+			t.Fatalf("%s: parse %q: %v", test.typ, typ.String(), err)
+		}
+		if !reflect.DeepEqual(parsed, typ) {
+			t.Errorf("%s: %q parsed to %+v, want %+v", test.typ, typ.String(), parsed, typ)
+		}
+	}
+
+	inner := []ArgumentMarshaling{{Name: "id", Type: "uint"}, {Name: "tags", Type: "string[]"}}
+	components := []ArgumentMarshaling{{Name: "amount", Type: "uint256"}, {Name: "data", Type: "bytes"}, {Name: "items", Type: "tuple[]", Components: inner}}
+	for _, test := range []struct {
+		typ  string
+		want string
+	}{
+		{"tuple", "(uint256,bytes,(uint256,string[])[])"},
+		{"tuple[2]", "(uint256,bytes,(uint256,string[])[])[2]"},
+		{"tuple[][3]", "(uint256,bytes,(uint256,string[])[])[][3]"},
+	} {
+		typ, err := NewType(test.typ, "", components)
+		if err != nil {
+			t.Fatalf("%s: %v", test.typ, err)
+		}
+		if typ.String() != test.want {
+			t.Errorf("%s: have %q, want %q", test.typ, typ.String(), test.want)
+		}
+		// the array suffix follows the component types
+		suffix := typ.String()[strings.LastIndex(typ.String(), ")")+1:]
+		parsed, err := NewType("tuple"+suffix, "", components)
+		if err != nil {
+			t.Fatalf("%s: parse tuple%s: %v", test.typ, suffix, err)
+		}
+		if parsed.String() != typ.String() {
+			t.Errorf("%s: have %q after parsing back, want %q", test.typ, parsed.String(), typ.String())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxCachedTypes bounds the number of parsed types NewType keeps, so parsing
+// an endless stream of distinct types can not grow the cache without limit.
+const maxCachedTypes = 4096
+
+var (
+	typeCacheLock sync.RWMutex
+	typeCache     = make(map[string]Type)
+)
+
+// typeCacheKey identifies the result of a NewType call. MaxArrayLength is part
+// of the key as it decides whether an array type parses at all.
+func typeCacheKey(t string, internalType string, components []ArgumentMarshaling) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(MaxArrayLength))
+	writeTypeCacheKey(&b, t, internalType, components)
+	return b.String()
+}
+
+func writeTypeCacheKey(b *strings.Builder, t string, internalType string, components []ArgumentMarshaling) {
+	b.WriteString("|" + strconv.Quote(t) + strconv.Quote(internalType))
+	if len(components) == 0 {
+		return
+	}
+	b.WriteString("(")
+	for _, c := range components {
+		b.WriteString(strconv.Quote(c.Name))
+		writeTypeCacheKey(b, c.Type, c.InternalType, c.Components)
+		b.WriteString(";")
+	}
+	b.WriteString(")")
+}
+
+func cachedType(key string) (Type, bool) {
+	typeCacheLock.RLock()
+	defer typeCacheLock.RUnlock()
+
+	typ, ok := typeCache[key]
+	return typ, ok
+}
+
+func cacheType(key string, typ Type) {
+	typeCacheLock.Lock()
+	defer typeCacheLock.Unlock()
+
+	if len(typeCache) < maxCachedTypes {
+		typeCache[key] = typ
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	typeMappingsLock sync.RWMutex
+	typeMappings     = make(map[string]func() interface{})
+)
+
+// RegisterTypeMapping makes the unpacker produce values built by factory for
+// arguments of the given ABI type, e.g. a `type Wei big.Int` for "uint256".
+// The factory returns either a fresh value or a pointer to one, and the
+// decoded value is converted into it, so its type must be convertible from
+// the built-in Go representation (or from what that points to).
+//
+// A registered mapping takes precedence over the built-in one for every
+// top-level argument of that type. Elements of arrays, slices and tuples keep
+// their built-in representation, as their containers are typed by Type.
+// Registering a nil factory removes the mapping. The type name is normalised,
+// so "uint" and "uint256" are the same entry; an invalid name panics.
+//
+// It is safe to register mappings concurrently with decoding.
+func RegisterTypeMapping(abiType string, factory func() interface{}) {
+	typ, err := NewType(abiType, "", nil)
+	if err != nil {
+		panic(fmt.Sprintf("abi: invalid type mapping: %v", err))
+	}
+	typeMappingsLock.Lock()
+	defer typeMappingsLock.Unlock()
+
+	if factory == nil {
+		delete(typeMappings, typ.String())
+		return
+	}
+	typeMappings[typ.String()] = factory
+}
+
+// mapGoType converts a value decoded into its built-in Go representation into
+// the one registered for its ABI type, if any.
+func mapGoType(t Type, value interface{}) (interface{}, error) {
+	typeMappingsLock.RLock()
+	factory := typeMappings[t.String()]
+	typeMappingsLock.RUnlock()
+
+	if factory == nil {
+		return value, nil
+	}
+	mapped := reflect.ValueOf(factory())
+	if !mapped.IsValid() {
+		return nil, fmt.Errorf("abi: type mapping for %v returned nil", t)
+	}
+	var dst reflect.Value
+	if mapped.Kind() == reflect.Ptr {
+		if mapped.IsNil() {
+			return nil, fmt.Errorf("abi: type mapping for %v returned a nil %v", t, mapped.Type())
+		}
+		dst = mapped.Elem()
+	} else {
+		dst = reflect.New(mapped.Type()).Elem()
+	}
+	src := reflect.ValueOf(value)
+	switch {
+	case src.Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+	case src.Kind() == reflect.Ptr && !src.IsNil() && src.Elem().Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Elem().Convert(dst.Type()))
+	default:
+		return nil, fmt.Errorf("abi: cannot map %v (%v) to %v", t, src.Type(), dst.Type())
+	}
+	if mapped.Kind() == reflect.Ptr {
+		return mapped.Interface(), nil
+	}
+	return dst.Interface(), nil
+}
@@ -117,24 +117,16 @@ func ReadFixedBytes(t Type, word []byte) (interface{}, error) {
 
 }
 
-func getFullElemSize(elem *Type) int {
-	//all other should be counted as 32 (slices have pointers to respective elements)
-	size := 32
-	//arrays wrap it, each element being the same size
-	for elem.T == ArrayTy {
-		size *= elem.Size
-		elem = elem.Elem
-	}
-	return size
-}
-
 // iteratively unpack elements
 func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error) {
 	if size < 0 {
 		return nil, fmt.Errorf("cannot marshal input to array, size is negative (%d)", size)
 	}
-	if start+32*size > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+32*size)
+	// Static elements (including static tuples and arrays) are encoded inline,
+	// dynamic ones take up a single offset word.
+	elemSize := getTypeSize(*t.Elem)
+	if start+elemSize*size > len(output) {
+		return nil, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+elemSize*size)
 	}
 
 	// this value will become our slice or our array, depending on the type
@@ -150,16 +142,9 @@ func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error)
 		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
 	}
 
-	// Arrays have packed elements, resulting in longer unpack steps.
-	// Slices have just 32 bytes per element (pointing to the contents).
-	elemSize := 32
-	if t.T == ArrayTy {
-		elemSize = getFullElemSize(t.Elem)
-	}
-
 	for i, j := start, 0; j < size; i, j = i+elemSize, j+1 {
 
-		inter, err := toGoType(i, *t.Elem, output)
+		inter, err := toBuiltinGoType(i, *t.Elem, output)
 		if err != nil {
 			return nil, err
 		}
@@ -176,7 +161,7 @@ func forTupleUnpack(t Type, output []byte) (interface{}, error) {
 	retval := reflect.New(t.getType()).Elem()
 	virtualArgs := 0
 	for index, elem := range t.TupleElems {
-		marshalledValue, err := toGoType((index+virtualArgs)*32, *elem, output)
+		marshalledValue, err := toBuiltinGoType((index+virtualArgs)*32, *elem, output)
 		if elem.T == ArrayTy && !isDynamicType(*elem) {
 			// If we have a static array, like [3]uint256, these are coded as
 			// just like uint256,uint256,uint256.
@@ -202,11 +187,21 @@ func forTupleUnpack(t Type, output []byte) (interface{}, error) {
 	return retval.Interface(), nil
 }
 
-// toGoType parses the output bytes and recursively assigns the value of these bytes
-// into a go type with accordance with the ABI spec.
+// toGoType parses the output bytes of a top-level argument into a go type,
+// honouring any mapping registered for its ABI type with RegisterTypeMapping.
 func toGoType(index int, t Type, output []byte) (interface{}, error) {
+	value, err := toBuiltinGoType(index, t, output)
+	if err != nil {
+		return nil, err
+	}
+	return mapGoType(t, value)
+}
+
+// toBuiltinGoType parses the output bytes and recursively assigns the value of these bytes
+// into a go type with accordance with the ABI spec.
+func toBuiltinGoType(index int, t Type, output []byte) (interface{}, error) {
 	if index+32 > len(output) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
+		return nil, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
 	}
 
 	var (
@@ -239,7 +234,10 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) {
 		return forEachUnpack(t, output[begin:], 0, length)
 	case ArrayTy:
 		if isDynamicType(*t.Elem) {
-			offset := int64(binary.BigEndian.Uint64(returnOutput[len(returnOutput)-8:]))
+			offset, err := tuplePointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
 			return forEachUnpack(t, output[offset:], 0, t.Size)
 		}
 		return forEachUnpack(t, output[index:], 0, t.Size)
@@ -266,6 +264,156 @@ func toGoType(index int, t Type, output []byte) (interface{}, error) {
 	}
 }
 
+// estimateAlloc mirrors toBuiltinGoType, reading the offsets and length
+// prefixes with the same bounds checks, and returns the number of bytes the
+// decoded value would take up without decoding it. Every word sized value is
+// counted as a full word, byte strings by their length.
+func estimateAlloc(index int, t Type, output []byte) (int, error) {
+	if index+32 > len(output) {
+		return 0, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
+	}
+	switch t.T {
+	case StringTy, BytesTy:
+		_, length, err := lengthPrefixPointsTo(index, output)
+		return length, err
+	case SliceTy:
+		begin, length, err := lengthPrefixPointsTo(index, output)
+		if err != nil {
+			return 0, err
+		}
+		return estimateElems(t, output[begin:], length)
+	case ArrayTy:
+		if isDynamicType(*t.Elem) {
+			offset, err := tuplePointsTo(index, output)
+			if err != nil {
+				return 0, err
+			}
+			return estimateElems(t, output[offset:], t.Size)
+		}
+		return estimateElems(t, output[index:], t.Size)
+	case TupleTy:
+		if isDynamicType(t) {
+			begin, err := tuplePointsTo(index, output)
+			if err != nil {
+				return 0, err
+			}
+			output, index = output[begin:], 0
+		} else {
+			output, index = output[index:], 0
+		}
+		total := 0
+		for _, elem := range t.TupleElems {
+			size, err := estimateAlloc(index, *elem, output)
+			if err != nil {
+				return 0, err
+			}
+			total += size
+			index += getTypeSize(*elem)
+		}
+		return total, nil
+	default:
+		return 32, nil
+	}
+}
+
+// estimateElems sums estimateAlloc over size elements of the array or slice
+// type t laid out from the start of output.
+func estimateElems(t Type, output []byte, size int) (int, error) {
+	elemSize := getTypeSize(*t.Elem)
+	if size < 0 || elemSize*size > len(output) {
+		return 0, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), elemSize*size)
+	}
+	total := 0
+	for i := 0; i < size; i++ {
+		n, err := estimateAlloc(i*elemSize, *t.Elem, output)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// readOffset reads the offset word at pos and returns the position it points
+// to when taken relative to base, checking that it lies within output.
+func readOffset(output []byte, pos int, base int) (int, error) {
+	if pos+32 > len(output) {
+		return 0, unpackErrorf(ErrInsufficientData, "offset at %d truncated: need %d bytes, have %d", pos, pos+32, len(output))
+	}
+	offset := new(big.Int).SetBytes(output[pos : pos+32])
+	if offset.BitLen() > 63 || offset.Int64() > int64(len(output)-base) {
+		return 0, unpackErrorf(ErrInsufficientData, "offset %v at %d points past the end (len=%d)", offset, pos, len(output))
+	}
+	return base + int(offset.Int64()), nil
+}
+
+// encodingEnd returns where the encoding of a t value stored from start ends,
+// following the offsets of dynamic values to the end of their tails. Strings
+// and bytes are counted with their padding.
+func encodingEnd(t Type, output []byte, start int) (int, error) {
+	if !isDynamicType(t) {
+		if end := start + getTypeSize(t); end <= len(output) {
+			return end, nil
+		}
+		return 0, unpackErrorf(ErrInsufficientData, "%v at %d truncated: need %d bytes, have %d", t, start, start+getTypeSize(t), len(output))
+	}
+	var elems []Type
+	switch t.T {
+	case StringTy, BytesTy, SliceTy:
+		if start+32 > len(output) {
+			return 0, unpackErrorf(ErrInsufficientData, "length of %v at %d truncated: need %d bytes, have %d", t, start, start+32, len(output))
+		}
+		length := new(big.Int).SetBytes(output[start : start+32])
+		if length.BitLen() > 63 || length.Int64() > int64(len(output)) {
+			return 0, unpackErrorf(ErrInsufficientData, "%v at %d truncated: length %v exceeds %d bytes", t, start, length, len(output))
+		}
+		n := int(length.Int64())
+		start += 32
+		if t.T != SliceTy {
+			if end := start + (n+31)/32*32; end <= len(output) {
+				return end, nil
+			}
+			return 0, unpackErrorf(ErrInsufficientData, "%v at %d truncated: need %d bytes, have %d", t, start-32, start+(n+31)/32*32, len(output))
+		}
+		for i := 0; i < n; i++ {
+			elems = append(elems, *t.Elem)
+		}
+	case ArrayTy:
+		for i := 0; i < t.Size; i++ {
+			elems = append(elems, *t.Elem)
+		}
+	case TupleTy:
+		for _, elem := range t.TupleElems {
+			elems = append(elems, *elem)
+		}
+	default:
+		return 0, fmt.Errorf("abi: unknown type %v", t.T)
+	}
+	// the heads of the elements start at start, the offsets of dynamic ones
+	// are relative to it
+	end, pos := start, start
+	for _, elem := range elems {
+		if pos+getTypeSize(elem) > len(output) {
+			return 0, unpackErrorf(ErrInsufficientData, "%v at %d truncated: need %d bytes, have %d", elem, pos, pos+getTypeSize(elem), len(output))
+		}
+		elemEnd := pos + getTypeSize(elem)
+		if isDynamicType(elem) {
+			elemStart, err := readOffset(output, pos, start)
+			if err != nil {
+				return 0, err
+			}
+			if elemEnd, err = encodingEnd(elem, output, elemStart); err != nil {
+				return 0, err
+			}
+		}
+		if elemEnd > end {
+			end = elemEnd
+		}
+		pos += getTypeSize(elem)
+	}
+	return end, nil
+}
+
 // interprets a 32 byte slice as an offset and then determines which indice to look to decode the type.
 func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err error) {
 	bigOffsetEnd := big.NewInt(0).SetBytes(output[index : index+32])
@@ -273,11 +421,11 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	outputLength := big.NewInt(int64(len(output)))
 
 	if bigOffsetEnd.Cmp(outputLength) > 0 {
-		return 0, 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", bigOffsetEnd, outputLength)
+		return 0, 0, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", bigOffsetEnd, outputLength)
 	}
 
 	if bigOffsetEnd.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi offset larger than int64: %v", bigOffsetEnd)
+		return 0, 0, unpackErrorf(ErrInsufficientData, "abi offset larger than int64: %v", bigOffsetEnd)
 	}
 
 	offsetEnd := int(bigOffsetEnd.Uint64())
@@ -287,11 +435,11 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	totalSize.Add(totalSize, bigOffsetEnd)
 	totalSize.Add(totalSize, lengthBig)
 	if totalSize.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi length larger than int64: %v", totalSize)
+		return 0, 0, unpackErrorf(ErrInsufficientData, "abi length larger than int64: %v", totalSize)
 	}
 
 	if totalSize.Cmp(outputLength) > 0 {
-		return 0, 0, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %v require %v", outputLength, totalSize)
+		return 0, 0, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go type: length insufficient %v require %v", outputLength, totalSize)
 	}
 	start = int(bigOffsetEnd.Uint64())
 	length = int(lengthBig.Uint64())
@@ -304,10 +452,10 @@ func tuplePointsTo(index int, output []byte) (start int, err error) {
 	outputLen := big.NewInt(int64(len(output)))
 
 	if offset.Cmp(big.NewInt(int64(len(output)))) > 0 {
-		return 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, outputLen)
+		return 0, unpackErrorf(ErrInsufficientData, "abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, outputLen)
 	}
 	if offset.BitLen() > 63 {
-		return 0, fmt.Errorf("abi offset larger than int64: %v", offset)
+		return 0, unpackErrorf(ErrInsufficientData, "abi offset larger than int64: %v", offset)
 	}
 	return int(offset.Uint64()), nil
 }
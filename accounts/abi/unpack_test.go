@@ -0,0 +1,529 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func TestUnpackTupleSlice(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"transfers","type":"tuple[]","components":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	type input struct {
+		To     common.Address
+		Amount *big.Int
+	}
+	type transfer struct {
+		Value *big.Int `abi:"amount"`
+		To    common.ContractAddress
+	}
+	for _, n := range []int{5, 0} {
+		in := make([]input, n)
+		for i := range in {
+			in[i].To[0] = byte(i + 1)
+			in[i].Amount = big.NewInt(int64(100 * (i + 1)))
+		}
+		packed, err := args.Pack(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []transfer
+		if err := args.Unpack(&out, packed); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != n {
+			t.Fatalf("got %d transfers, want %d", len(out), n)
+		}
+		for i := range out {
+			if out[i].To != in[i].To.ToCaddr() {
+				t.Errorf("transfer %d: address mismatch", i)
+			}
+			if out[i].Value.Cmp(in[i].Amount) != 0 {
+				t.Errorf("transfer %d: got amount %v, want %v", i, out[i].Value, in[i].Amount)
+			}
+		}
+	}
+}
+
+func TestUnpackTupleFieldCollision(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"transfer","type":"tuple","components":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	var in struct {
+		To     common.Address
+		Amount *big.Int
+	}
+	in.To[0], in.Amount = 1, big.NewInt(100)
+	packed, err := args.Pack(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// to falls back to the first field by position, which amount takes by name
+	var out struct {
+		Amount *big.Int
+		Memo   string
+	}
+	if err := args.Unpack(&out, packed); !errors.Is(err, ErrTupleMismatch) {
+		t.Errorf("have %v, want an error of kind %v", err, ErrTupleMismatch)
+	}
+}
+
+func TestUnpackValuesAll(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"a","type":"uint256"},{"name":"b","type":"bool"},{"name":"c","type":"string"}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	packed, err := args.Pack(big.NewInt(1), true, "sero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed[63] = 2 // not a valid bool
+	values, errs := args.UnpackValuesAll(packed)
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if errs[1] == nil || values[1] != nil {
+		t.Fatalf("expected error for the bool, got %v (%v)", errs[1], values[1])
+	}
+	if values[0].(*big.Int).Int64() != 1 || values[2].(string) != "sero" {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+type testWei big.Int
+
+func TestRegisterTypeMapping(t *testing.T) {
+	RegisterTypeMapping("uint", func() interface{} { return new(testWei) })
+	defer RegisterTypeMapping("uint256", nil)
+
+	uint256Type, _ := NewType("uint256", "", nil)
+	uint8Type, _ := NewType("uint8", "", nil)
+	args := Arguments{{Name: "amount", Type: uint256Type}, {Name: "decimals", Type: uint8Type}}
+	data, err := args.Pack(big.NewInt(1000), uint8(18))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := args.UnpackValues(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wei, ok := values[0].(*testWei)
+	if !ok {
+		t.Fatalf("uint256 value: have %T, want *testWei", values[0])
+	}
+	if (*big.Int)(wei).Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("uint256 value: have %v, want 1000", (*big.Int)(wei))
+	}
+	if _, ok := values[1].(uint8); !ok {
+		t.Errorf("uint8 value: have %T, want uint8", values[1])
+	}
+
+	var out struct {
+		Amount   *testWei
+		Decimals uint8
+	}
+	if err := args.Unpack(&out, data); err != nil {
+		t.Fatal(err)
+	}
+	if out.Amount == nil || (*big.Int)(out.Amount).Cmp(big.NewInt(1000)) != 0 || out.Decimals != 18 {
+		t.Errorf("struct unpack: have %v %d", (*big.Int)(out.Amount), out.Decimals)
+	}
+
+	RegisterTypeMapping("uint256", nil)
+	if values, _ = args.UnpackValues(data); reflect.TypeOf(values[0]) != reflect.TypeOf(new(big.Int)) {
+		t.Errorf("after removal: have %T, want *big.Int", values[0])
+	}
+}
+
+func TestUnpackToChan(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "a", Type: uint256Type}, {Name: "b", Type: stringType}}
+	data, err := args.Pack(big.NewInt(7), "sero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan interface{}, 2)
+	if err := args.UnpackToChan(data, out); err != nil {
+		t.Fatal(err)
+	}
+	if v := <-out; v.(*big.Int).Int64() != 7 {
+		t.Errorf("first value: have %v, want 7", v)
+	}
+	if v := <-out; v.(string) != "sero" {
+		t.Errorf("second value: have %v, want sero", v)
+	}
+
+	// the string's offset word points past the truncated data
+	if err := args.UnpackToChan(data[:64], out); err == nil {
+		t.Fatal("expected error for truncated data")
+	}
+	if v := <-out; v.(*big.Int).Int64() != 7 {
+		t.Errorf("value before the error: have %v, want 7", v)
+	}
+	select {
+	case v := <-out:
+		t.Errorf("unexpected value %v", v)
+	default:
+	}
+}
+
+func TestAssetTupleRoundTrip(t *testing.T) {
+	for _, currencyType := range []string{"bytes32", "uint256"} {
+		assetType, err := NewType("tuple", "", []ArgumentMarshaling{{Name: "currency", Type: currencyType}, {Name: "value", Type: "uint256"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := Arguments{{Name: "asset", Type: assetType}}
+		in, err := utils.NewAsset("SERO", utils.NewU256(1000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		packed, err := args.Pack(in)
+		if err != nil {
+			t.Fatalf("%s: %v", currencyType, err)
+		}
+		if !bytes.Equal(packed[:32], in.Currency[:]) {
+			t.Errorf("%s: currency packed as %x", currencyType, packed[:32])
+		}
+		var out utils.Asset
+		if err := args.Unpack(&out, packed); err != nil {
+			t.Fatalf("%s: %v", currencyType, err)
+		}
+		if out.Currency != in.Currency || out.Value.ToIntRef().Cmp(in.Value.ToIntRef()) != 0 {
+			t.Errorf("%s: have %v %v, want %v %v", currencyType, utils.Uint256ToCurrency(&out.Currency), out.Value.ToIntRef(), "SERO", 1000)
+		}
+	}
+}
+
+func TestStaticTupleArrayRoundTrip(t *testing.T) {
+	pairType, err := NewType("tuple[2]", "", []ArgumentMarshaling{{Name: "to", Type: "address"}, {Name: "value", Type: "uint256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "pairs", Type: pairType}, {Name: "n", Type: uint256Type}, {Name: "memo", Type: stringType}}
+
+	type pair struct {
+		To    common.ContractAddress
+		Value *big.Int
+	}
+	in := [2]pair{{common.ContractAddress{1}, big.NewInt(10)}, {common.ContractAddress{2}, big.NewInt(20)}}
+	packed, err := args.Pack(in, big.NewInt(3), "sero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 words of tuples, n, the string offset, its length and one data word
+	if len(packed) != 32*8 {
+		t.Fatalf("have %d bytes, want %d", len(packed), 32*8)
+	}
+	var out struct {
+		Pairs [2]pair
+		N     *big.Int
+		Memo  string
+	}
+	if err := args.Unpack(&out, packed); err != nil {
+		t.Fatal(err)
+	}
+	for i := range in {
+		want := common.BytesToContractAddress(convertToPkr(in[i].To[:]))
+		if out.Pairs[i].To != want || out.Pairs[i].Value.Cmp(in[i].Value) != 0 {
+			t.Errorf("pair %d: have %x %v, want %x %v", i, out.Pairs[i].To, out.Pairs[i].Value, want, in[i].Value)
+		}
+	}
+	if out.N.Int64() != 3 || out.Memo != "sero" {
+		t.Errorf("trailing arguments: have %v %q", out.N, out.Memo)
+	}
+}
+
+type settableTransfer struct {
+	fields map[string]interface{}
+}
+
+func (s *settableTransfer) SetABIField(name string, v interface{}) error {
+	if name == "bad" {
+		return errors.New("rejected")
+	}
+	s.fields[name] = v
+	return nil
+}
+
+func TestUnpackIntoSetter(t *testing.T) {
+	uint256Type, _ := NewType("uint256", "", nil)
+	stringType, _ := NewType("string", "", nil)
+	args := Arguments{{Name: "from", Type: uint256Type, Indexed: true}, {Name: "amount", Type: uint256Type}, {Name: "memo", Type: stringType}}
+	data, err := args.NonIndexed().Pack(big.NewInt(5), "sero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unpack := range []func(interface{}, []byte) error{args.Unpack, args.Prepare().Unpack} {
+		out := &settableTransfer{fields: make(map[string]interface{})}
+		if err := unpack(out, data); err != nil {
+			t.Fatal(err)
+		}
+		if len(out.fields) != 2 || out.fields["amount"].(*big.Int).Int64() != 5 || out.fields["memo"] != "sero" {
+			t.Errorf("have %v", out.fields)
+		}
+	}
+	bad := Arguments{{Name: "bad", Type: uint256Type}}
+	if err := bad.Unpack(&settableTransfer{fields: make(map[string]interface{})}, data[:32]); err == nil {
+		t.Error("expected setter error")
+	}
+}
+
+func TestEstimateAlloc(t *testing.T) {
+	def := `[{"name":"a","type":"uint256"},{"name":"b","type":"bytes"},{"name":"c","type":"string[]"},{"name":"d","type":"tuple[]","components":[{"name":"x","type":"uint64"},{"name":"y","type":"bytes"}]}]`
+	var args Arguments
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	d := []struct {
+		X uint64
+		Y []byte
+	}{{1, make([]byte, 100)}}
+	data, err := args.Pack(big.NewInt(1), make([]byte, 40), []string{"ab", "cde"}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := args.EstimateAlloc(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 32 + 40 + (2 + 3) + (32 + 100); size != want {
+		t.Errorf("have %d, want %d", size, want)
+	}
+
+	// a length prefix claiming more bytes than the data holds
+	bytesType, _ := NewType("bytes", "", nil)
+	forged := make([]byte, 64)
+	forged[31] = 32
+	forged[62], forged[63] = 0xff, 0xff
+	if _, err := (Arguments{{Name: "b", Type: bytesType}}).EstimateAlloc(forged); err == nil {
+		t.Error("expected error for a length beyond the data")
+	}
+}
+
+func TestUnpackRevert(t *testing.T) {
+	if common.Bytes2Hex(revertSelector) != "08c379a0" {
+		t.Fatalf("have selector %x, want 08c379a0", revertSelector)
+	}
+	data := common.FromHex("0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000")
+	if reason, err := UnpackRevert(data); err != nil || reason != "revert reason" {
+		t.Errorf("have %q %v, want %q", reason, err, "revert reason")
+	}
+	if _, err := UnpackRevert(nil); err != ErrNoRevertReason {
+		t.Errorf("empty data: have %v, want %v", err, ErrNoRevertReason)
+	}
+	if _, err := UnpackRevert([]byte{1, 2, 3, 4, 5}); err != ErrNotRevertReason {
+		t.Errorf("other selector: have %v, want %v", err, ErrNotRevertReason)
+	}
+	if _, err := UnpackRevert(data[:40]); err == nil {
+		t.Error("expected error for truncated reason")
+	}
+}
+
+func TestUnpackValuesMalformed(t *testing.T) {
+	signatures := [][]string{
+		{"uint256", "string"},
+		{"bytes", "bool"},
+		{"uint8[]", "address"},
+		{"string[2]", "uint64"},
+		{"string[]", "bytes32"},
+		{"uint256[2][3]", "bytes"},
+		{"int256[][]"},
+		{"bytes[2][]"},
+		{"function", "bytes4"},
+	}
+	tupleType, err := NewType("tuple", "", []ArgumentMarshaling{{Name: "memo", Type: "string"}, {Name: "data", Type: "bytes[]"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tupleSliceType, _ := NewType("tuple[]", "", []ArgumentMarshaling{{Name: "n", Type: "uint256"}, {Name: "memo", Type: "string"}})
+
+	var argSets []Arguments
+	for _, sig := range signatures {
+		var args Arguments
+		for _, typ := range sig {
+			abiType, err := NewType(typ, "", nil)
+			if err != nil {
+				t.Fatalf("%s: %v", typ, err)
+			}
+			args = append(args, Argument{Type: abiType})
+		}
+		argSets = append(argSets, args)
+	}
+	argSets = append(argSets, Arguments{{Type: tupleType}}, Arguments{{Type: tupleSliceType}})
+
+	rnd := rand.New(rand.NewSource(1))
+	words := [][]byte{
+		common.LeftPadBytes([]byte{0x20}, 32),
+		common.LeftPadBytes([]byte{0x40}, 32),
+		common.LeftPadBytes([]byte{0xff, 0xff}, 32),
+		common.LeftPadBytes(big.NewInt(1<<62).Bytes(), 32),
+		common.LeftPadBytes([]byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xe0}, 32),
+		MaxUint256.Bytes(),
+		make([]byte, 32),
+	}
+	for _, args := range argSets {
+		for i := 0; i < 500; i++ {
+			var data []byte
+			for n := rnd.Intn(10); n > 0; n-- {
+				if rnd.Intn(3) == 0 {
+					word := make([]byte, 32)
+					rnd.Read(word)
+					data = append(data, word...)
+				} else {
+					data = append(data, words[rnd.Intn(len(words))]...)
+				}
+			}
+			data = data[:rnd.Intn(len(data)+1)]
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("%v: panic unpacking %x: %v", args, data, r)
+					}
+				}()
+				args.UnpackValues(data)
+				args.ByteRanges(data)
+			}()
+		}
+	}
+}
+
+func TestUnpackNegativeIntegers(t *testing.T) {
+	minInt256, _ := new(big.Int).SetString("-57896044618658097711785492504343953926634992332820282019728792003956564819968", 10)
+	tests := []struct {
+		typ  string
+		word string
+		want *big.Int
+	}{
+		{"int256", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", big.NewInt(-1)},
+		{"int256", "8000000000000000000000000000000000000000000000000000000000000000", minInt256},
+		{"int256", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff6", big.NewInt(-10)},
+		{"int256", "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", MaxInt256},
+		{"int128", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff85", big.NewInt(-123)},
+		{"int24", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80000", big.NewInt(-1 << 19)},
+	}
+	for _, test := range tests {
+		typ, _ := NewType(test.typ, "", nil)
+		args := Arguments{{Type: typ}}
+		values, err := args.UnpackValues(common.FromHex(test.word))
+		if err != nil {
+			t.Fatalf("%s %s: %v", test.typ, test.word, err)
+		}
+		if have, ok := values[0].(*big.Int); !ok || have.Cmp(test.want) != 0 {
+			t.Errorf("%s %s: have %v, want %v", test.typ, test.word, values[0], test.want)
+		}
+		packed, err := args.Pack(test.want)
+		if err != nil || common.Bytes2Hex(packed) != test.word {
+			t.Errorf("%s %v: packed to %x %v, want %s", test.typ, test.want, packed, err, test.word)
+		}
+	}
+}
+
+func TestUnpackErrorKinds(t *testing.T) {
+	var args Arguments
+	def := `[{"name":"amount","type":"uint256"},{"name":"memo","type":"string"}]`
+	if err := json.Unmarshal([]byte(def), &args); err != nil {
+		t.Fatal(err)
+	}
+	packed, err := args.Pack(big.NewInt(5), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		result struct {
+			Amount *big.Int
+			Memo   string
+		}
+		wrongType struct {
+			Amount string
+			Memo   string
+		}
+		missing struct {
+			Amount *big.Int
+		}
+		short  [1]interface{}
+		scalar *big.Int
+	)
+	if err := args.Unpack(&result, packed); err != nil || result.Amount.Int64() != 5 || result.Memo != "hello" {
+		t.Fatalf("have %+v %v", result, err)
+	}
+	tests := []struct {
+		name string
+		v    interface{}
+		data []byte
+		kind error
+	}{
+		{"truncated head", &result, packed[:40], ErrInsufficientData},
+		{"truncated tail", &result, packed[:len(packed)-32], ErrInsufficientData},
+		{"offset past the end", &result, append(common.CopyBytes(packed[:32]), common.LeftPadBytes([]byte{0xff, 0xff}, 32)...), ErrInsufficientData},
+		{"wrong field type", &wrongType, packed, ErrTypeMismatch},
+		{"missing field", &missing, packed, ErrFieldNotFound},
+		{"short array", &short, packed, ErrTupleMismatch},
+		{"scalar", &scalar, packed, ErrTupleMismatch},
+	}
+	for _, test := range tests {
+		err := args.Unpack(test.v, test.data)
+		if !errors.Is(err, test.kind) {
+			t.Errorf("%s: have %v, want an error of kind %v", test.name, err, test.kind)
+		}
+		for _, other := range []error{ErrInsufficientData, ErrTypeMismatch, ErrFieldNotFound, ErrTupleMismatch} {
+			if other != test.kind && errors.Is(err, other) {
+				t.Errorf("%s: %v is also of kind %v", test.name, err, other)
+			}
+		}
+	}
+
+	// truncated data is reported the same way when only measuring it
+	var pair Arguments
+	if err := json.Unmarshal([]byte(`[{"name":"pair","type":"uint256[2]"}]`), &pair); err != nil {
+		t.Fatal(err)
+	}
+	offsetPastEnd := append(common.CopyBytes(packed[:32]), common.LeftPadBytes([]byte{0xff, 0xff}, 32)...)
+	truncated := []struct {
+		name string
+		fn   func() error
+	}{
+		{"estimate truncated head", func() error { _, err := args.EstimateAlloc(packed[:40]); return err }},
+		{"estimate truncated array", func() error { _, err := pair.EstimateAlloc(packed[:40]); return err }},
+		{"ranges truncated head", func() error { _, err := args.ByteRanges(packed[:40]); return err }},
+		{"ranges offset past the end", func() error { _, err := args.ByteRanges(offsetPastEnd); return err }},
+		{"ranges truncated tail", func() error { _, err := args.ByteRanges(packed[:len(packed)-32]); return err }},
+	}
+	for _, test := range truncated {
+		if err := test.fn(); !errors.Is(err, ErrInsufficientData) {
+			t.Errorf("%s: have %v, want an error of kind %v", test.name, err, ErrInsufficientData)
+		}
+	}
+}
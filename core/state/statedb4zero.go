@@ -105,7 +105,7 @@ func (self *zeroDB) GlobalGetter() serodb.Getter {
 	return self.db.db.TrieDB().DiskDB()
 }
 
-var StakeDB = consensus.DBObj{"STAKE$BLOCK$INDEX"}
+var StakeDB = consensus.DBObj{Pre: "STAKE$BLOCK$INDEX"}
 
 func (self *StateDB) GetStakeCons() *consensus.Cons {
 	if self.stakeState == nil {
@@ -586,7 +586,11 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs
 		e = errors.New("not support")
 		return
 	} else {
-		if pretx, e = s.b.GenTx(args.toTxParam(state, fromAccount)); e != nil {
+		var txParam prepare.PreTxParam
+		if txParam, e = args.toTxParam(state, fromAccount); e != nil {
+			return
+		}
+		if pretx, e = s.b.GenTx(txParam); e != nil {
 			return
 		}
 		log.Info("ToTxParam", "utxos", len(pretx.Ins))
@@ -1124,6 +1128,12 @@ func (s *Smbol) IsNotSero() bool {
 	return !s.IsSero()
 }
 
+// Currency returns the id of the currency named by the symbol. Like IsSero it
+// ignores the case and surrounding spaces of the symbol.
+func (s Smbol) Currency() (c_type.Uint256, error) {
+	return utils.CurrencyToUint256(strings.ToUpper(strings.TrimSpace(string(s))))
+}
+
 func (s Smbol) String() string {
 	return string(s)
 }
@@ -1223,8 +1233,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		state.AddBalance(common.BytesToAddress(args.To[:]), "SERO", fee)
 		fee = new(big.Int).Div(fee.Mul(fee, m), d)
 	}
+	gasCurrency, err := args.GasCurrency.Currency()
+	if err != nil {
+		return nil, 0, false, err
+	}
 	feeToken := assets.Token{
-		utils.CurrencyToUint256(string(args.GasCurrency)),
+		gasCurrency,
 		utils.U256(*fee),
 	}
 	var fromPkr c_type.PKr
@@ -1898,7 +1912,7 @@ func (args *SendTxArgs) toAsset() assets.Asset {
 	}
 }
 
-func (args *SendTxArgs) toTxParam(state *state.StateDB, fromAccount accounts.Account) (txParam prepare.PreTxParam) {
+func (args *SendTxArgs) toTxParam(state *state.StateDB, fromAccount accounts.Account) (txParam prepare.PreTxParam, err error) {
 
 	var refundPkr c_type.PKr
 	txParam.GasPrice = (*big.Int)(args.GasPrice)
@@ -1946,8 +1960,12 @@ func (args *SendTxArgs) toTxParam(state *state.StateDB, fromAccount accounts.Acc
 		receptions := []prepare.Reception{{Addr: args.To.ToPkr(false), Asset: asset}}
 		txParam.Receptions = receptions
 	}
+	gasCurrency, err := args.GasCurrency.Currency()
+	if err != nil {
+		return
+	}
 	feeAsset := assets.Token{
-		utils.CurrencyToUint256(string(args.GasCurrency)),
+		gasCurrency,
 		utils.U256(*feevalue),
 	}
 	txParam.Fee = feeAsset
@@ -1970,7 +1988,7 @@ func stringToUint512(str string) c_type.Uint512 {
 	return ret
 }
 
-func (args *SendTxArgs) toCreatePkg(state *state.StateDB, fromAccount accounts.Account) (txParam prepare.PreTxParam) {
+func (args *SendTxArgs) toCreatePkg(state *state.StateDB, fromAccount accounts.Account) (txParam prepare.PreTxParam, err error) {
 	var toPkr c_type.PKr
 	txParam.GasPrice = (*big.Int)(args.GasPrice)
 	txParam.From = fromAccount.Address.ToUint512()
@@ -1982,8 +2000,12 @@ func (args *SendTxArgs) toCreatePkg(state *state.StateDB, fromAccount accounts.A
 	} else {
 		toPkr = args.To.ToPkr(false)
 	}
+	gasCurrency, err := args.GasCurrency.Currency()
+	if err != nil {
+		return
+	}
 	feeToken := assets.Token{
-		utils.CurrencyToUint256(string(args.GasCurrency)),
+		gasCurrency,
 		utils.U256(*feevalue),
 	}
 	txParam.RefundTo = fromAccount.GetPkr(nil).NewRef()
@@ -2018,7 +2040,11 @@ func (s *PublicTransactionPoolAPI) GenTx(ctx context.Context, param GenTxArgs) (
 		return nil, err
 	}
 
-	return s.b.GenTx(param.toTxParam())
+	txParam, err := param.toTxParam()
+	if err != nil {
+		return nil, err
+	}
+	return s.b.GenTx(txParam)
 }
 
 func commitSendTxArgs(ctx context.Context, b Backend, args SendTxArgs) (common.Hash, error) {
@@ -2041,7 +2067,10 @@ func commitSendTxArgs(ctx context.Context, b Backend, args SendTxArgs) (common.H
 	if !seroparam.IsExchange() {
 		return common.Hash{}, errors.New("not support")
 	} else {
-		txParam := args.toTxParam(state, fromAccount)
+		txParam, err := args.toTxParam(state, fromAccount)
+		if err != nil {
+			return common.Hash{}, err
+		}
 		txhash, err := commitPreTx(txParam, b, args.To)
 		if err != nil {
 			return common.Hash{}, err
@@ -2135,7 +2164,10 @@ func (s *PublicTransactionPoolAPI) CreatePkg(ctx context.Context, args SendTxArg
 	}
 
 	if seroparam.IsExchange() {
-		txParam := args.toCreatePkg(state)
+		txParam, err := args.toCreatePkg(state)
+		if err != nil {
+			return common.Hash{}, err
+		}
 		pretx, gtx, err := exchange.CurrentExchange().GenTxWithSign(txParam)
 		if err != nil {
 			return common.Hash{}, err
@@ -2199,7 +2231,7 @@ func (args *ClosePkgArgs) toTxParam(fromAccount accounts.Account) (txParam prepa
 	txParam.GasPrice = (*big.Int)(args.GasPrice)
 	feevalue := defaultFee(args.GasPrice, args.Gas)
 	feeToken := assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*feevalue),
 	}
 	txParam.From = fromAccount.Address.ToUint512()
@@ -2260,7 +2292,7 @@ func (args *TransferPkgArgs) toTransaction(state *state.StateDB) (*types.Transac
 
 	txt := &ztx.T{
 		Fee: assets.Token{
-			utils.CurrencyToUint256(params.DefaultCurrency),
+			utils.SeroCurrency,
 			utils.U256(*fee),
 		},
 		PkgTransfer: &ztx.PkgTransfer{*args.PkgId, pkr},
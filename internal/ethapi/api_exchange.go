@@ -130,14 +130,22 @@ func (s *PublicExchangeAPI) GenTx(ctx context.Context, param GenTxArgs) (*txtool
 		return nil, err
 	}
 
-	return s.b.GenTx(param.toTxParam())
+	txParam, err := param.toTxParam()
+	if err != nil {
+		return nil, err
+	}
+	return s.b.GenTx(txParam)
 }
 
 func (s *PublicExchangeAPI) GenTxWithSign(ctx context.Context, param GenTxArgs) (*txtool.GTx, error) {
 	if err := param.check(); err != nil {
 		return nil, err
 	}
-	txParam, tx, e := exchange.CurrentExchange().GenTxWithSign(param.toTxParam())
+	preTxParam, err := param.toTxParam()
+	if err != nil {
+		return nil, err
+	}
+	txParam, tx, e := exchange.CurrentExchange().GenTxWithSign(preTxParam)
 	if tx != nil {
 		for _, in := range txParam.Ins {
 			tx.Roots = append(tx.Roots, in.Out.Root)
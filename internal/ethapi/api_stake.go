@@ -105,7 +105,7 @@ func (args *BuyShareTxArg) toPreTxParam(fromAccount accounts.Account) prepare.Pr
 	preTx.From = fromAccount.Address.ToUint512()
 	preTx.RefundTo = fromAccount.GetPkr(nil).NewRef()
 	preTx.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(*args.Gas)), args.GasPrice.ToInt())),
 	}
 	preTx.GasPrice = (*big.Int)(args.GasPrice)
@@ -214,7 +214,7 @@ func (args *RegistStakePoolTxArg) toPreTxParam(fromAccount accounts.Account) pre
 	preTx := prepare.PreTxParam{}
 	preTx.From = fromAccount.Address.ToUint512()
 	preTx.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(*args.Gas)), args.GasPrice.ToInt())),
 	}
 	preTx.GasPrice = (*big.Int)(args.GasPrice)
@@ -314,7 +314,7 @@ func (s *PublicStakeApI) CloseStakePool(ctx context.Context, from address.MixBas
 	preTx.From = fromAccount.Address.ToUint512()
 	preTx.RefundTo = &fromPkr
 	preTx.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(25000)), new(big.Int).SetUint64(defaultGasPrice))),
 	}
 	preTx.GasPrice = new(big.Int).SetUint64(defaultGasPrice)
@@ -373,7 +373,7 @@ func (s *PublicStakeApI) ModifyStakePoolFee(ctx context.Context, from address.Mi
 	preTx.From = fromAccount.Address.ToUint512()
 	preTx.RefundTo = &fromPkr
 	preTx.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(25000)), new(big.Int).SetUint64(defaultGasPrice))),
 	}
 	preTx.GasPrice = new(big.Int).SetUint64(defaultGasPrice)
@@ -427,7 +427,7 @@ func (s *PublicStakeApI) ModifyStakePoolVote(ctx context.Context, from address.M
 	preTx.From = fromAccount.Address.ToUint512()
 	preTx.RefundTo = &fromPkr
 	preTx.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(25000)), new(big.Int).SetUint64(defaultGasPrice))),
 	}
 	preTx.GasPrice = new(big.Int).SetUint64(defaultGasPrice)
@@ -471,7 +471,10 @@ func (s *PublicStakeApI) PoolState(ctx context.Context, poolId common.Hash) (map
 
 	if poolState.LastPayTime != 0 {
 		header, _ := s.b.HeaderByNumber(ctx, rpc.BlockNumber(poolState.LastPayTime))
-		snapshot := stake.GetStakePoolByBlockNumber(s.b.ChainDb(), poolId, header.Hash(), header.Number.Uint64())
+		snapshot, err := stake.GetStakePoolByBlockNumber(s.b.ChainDb(), poolId, header.Hash(), header.Number.Uint64())
+		if err != nil {
+			return nil, err
+		}
 		if snapshot != nil {
 			ret["returnProfit"] = hexutil.Big(*snapshot.Profit)
 		}
@@ -694,7 +697,10 @@ func newRPCStatisticsShare(mg *accounts.Manager, shares []*stake.Share, api *Pub
 		remain := share.InitNum
 		if share.LastPayTime != 0 {
 			header, _ := api.b.HeaderByNumber(ctx, rpc.BlockNumber(share.LastPayTime))
-			snapshot := stake.GetShareByBlockNumber(api.b.ChainDb(), common.BytesToHash(share.Id()), header.Hash(), header.Number.Uint64())
+			snapshot, err := stake.GetShareByBlockNumber(api.b.ChainDb(), common.BytesToHash(share.Id()), header.Hash(), header.Number.Uint64())
+			if err != nil {
+				log.Error("Failed to get share snapshot", "id", hexutil.Encode(share.Id()), "number", share.LastPayTime, "err", err)
+			}
 			if snapshot != nil {
 				if snapshot.Status == 0 {
 					remain = snapshot.Num + snapshot.WillVoteNum
@@ -791,7 +797,10 @@ func (s *PublicStakeApI) GetShare(ctx context.Context, shareId common.Hash) map[
 
 	if share.LastPayTime != 0 {
 		header, _ := s.b.HeaderByNumber(ctx, rpc.BlockNumber(share.LastPayTime))
-		snapshot := stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
+		snapshot, err := stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
+		if err != nil {
+			log.Error("Failed to get share snapshot", "id", shareId.Hex(), "number", share.LastPayTime, "err", err)
+		}
 		if snapshot != nil {
 			if snapshot.Status == 1 {
 				ret["returnNum"] = hexutil.Uint64(snapshot.InitNum - snapshot.WillVoteNum)
@@ -821,7 +830,11 @@ func (s *PublicStakeApI) GetStakeInfo(ctx context.Context, poolId common.Hash, s
 		if err != nil || header == nil {
 			return
 		}
-		shareList, poolList := stake.GetBlockRecords(s.b.ChainDb(), header.Hash(), uint64(start))
+		shareList, poolList, err := stake.GetBlockRecords(s.b.ChainDb(), header.Hash(), uint64(start))
+		if err != nil {
+			log.Error("Failed to get stake block records", "number", uint64(start), "err", err)
+			return
+		}
 		for _, each := range shareList {
 			if each.PoolId != nil && *each.PoolId == poolId {
 				share := map[string]interface{}{}
@@ -876,9 +889,8 @@ func (s *PublicStakeApI) Shares(ctx context.Context) (shares []*stake.Share) {
 	return stakeservice.CurrentStakeService().Shares()
 }
 
-func (s *PublicStakeApI) GetShareAtNumber(ctx context.Context, shareId common.Hash, num hexutil.Uint64) (share *stake.Share) {
+func (s *PublicStakeApI) GetShareAtNumber(ctx context.Context, shareId common.Hash, num hexutil.Uint64) (share *stake.Share, err error) {
 
 	header, _ := s.b.HeaderByNumber(ctx, rpc.BlockNumber(num))
-	share = stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
-	return
+	return stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
 }
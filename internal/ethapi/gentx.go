@@ -56,14 +56,18 @@ type PkgCreateArgs struct {
 	Memo     c_type.Uint512
 }
 
-func (self *PkgCreateArgs) toCmd() *prepare.PkgCreateCmd {
+func (self *PkgCreateArgs) toCmd() (*prepare.PkgCreateCmd, error) {
 	if self == nil {
-		return nil
+		return nil, nil
 	}
 	asset := assets.Asset{}
 	if !self.Currency.IsEmpty() && self.Value != nil {
+		currency, err := self.Currency.Currency()
+		if err != nil {
+			return nil, err
+		}
 		asset.Tkn = &assets.Token{
-			utils.CurrencyToUint256(string(self.Currency)),
+			currency,
 			utils.U256(*self.Value.ToInt()),
 		}
 	}
@@ -72,7 +76,7 @@ func (self *PkgCreateArgs) toCmd() *prepare.PkgCreateCmd {
 		self.PKr.ToPKr(),
 		asset,
 		self.Memo,
-	}
+	}, nil
 }
 
 type BuyShareArgs struct {
@@ -128,20 +132,28 @@ type ContractArgs struct {
 	Data     hexutil.Bytes
 }
 
-func (self *ContractArgs) toCmd() *stx.ContractCmd {
+func (self *ContractArgs) toCmd() (*stx.ContractCmd, error) {
 	if self == nil {
-		return nil
+		return nil, nil
 	}
 	asset := assets.Asset{}
 	if !self.Currency.IsEmpty() && self.Value != nil {
+		currency, err := self.Currency.Currency()
+		if err != nil {
+			return nil, err
+		}
 		asset.Tkn = &assets.Token{
-			utils.CurrencyToUint256(string(self.Currency)),
+			currency,
 			utils.U256(*self.Value.ToInt()),
 		}
 	}
 	if !self.Category.IsEmpty() && self.Tkt != nil {
+		category, err := self.Category.Currency()
+		if err != nil {
+			return nil, err
+		}
 		asset.Tkt = &assets.Ticket{
-			utils.CurrencyToUint256(string(self.Category)),
+			category,
 			*self.Tkt.HashToUint256(),
 		}
 	}
@@ -155,7 +167,7 @@ func (self *ContractArgs) toCmd() *stx.ContractCmd {
 		asset,
 		pkr,
 		self.Data,
-	}
+	}, nil
 }
 
 type CmdsArgs struct {
@@ -172,16 +184,24 @@ type CmdsArgs struct {
 	PkgClose    *PkgCloseArgs
 }
 
-func (self *CmdsArgs) toCmds() prepare.Cmds {
+func (self *CmdsArgs) toCmds() (prepare.Cmds, error) {
+	contract, err := self.Contract.toCmd()
+	if err != nil {
+		return prepare.Cmds{}, err
+	}
+	pkgCreate, err := self.PkgCreate.toCmd()
+	if err != nil {
+		return prepare.Cmds{}, err
+	}
 	return prepare.Cmds{
 		self.BuyShare.toCmd(),
 		self.RegistPool.toCmd(),
 		self.ClosePool.toCmd(),
-		self.Contract.toCmd(),
-		self.PkgCreate.toCmd(),
+		contract,
+		pkgCreate,
 		self.PkgTransfer.toCmd(),
 		self.PkgClose.toCmd(),
-	}
+	}, nil
 }
 
 type GenTxArgs struct {
@@ -232,7 +252,7 @@ func (args GenTxArgs) check() error {
 
 }
 
-func (args GenTxArgs) toTxParam() prepare.PreTxParam {
+func (args GenTxArgs) toTxParam() (prepare.PreTxParam, error) {
 	gasPrice := args.GasPrice.ToInt()
 
 	if gasPrice.Sign() == 0 {
@@ -275,7 +295,10 @@ func (args GenTxArgs) toTxParam() prepare.PreTxParam {
 	}
 	cmds := prepare.Cmds{}
 	if args.Cmds != nil {
-		cmds = args.Cmds.toCmds()
+		var err error
+		if cmds, err = args.Cmds.toCmds(); err != nil {
+			return prepare.PreTxParam{}, err
+		}
 	}
 	return prepare.PreTxParam{
 		args.From.ToUint512(),
@@ -283,10 +306,10 @@ func (args GenTxArgs) toTxParam() prepare.PreTxParam {
 		receptions,
 		cmds,
 		assets.Token{
-			utils.CurrencyToUint256("SERO"),
+			utils.SeroCurrency,
 			utils.U256(*big.NewInt(0).Mul(big.NewInt(int64(args.Gas)), args.GasPrice.ToInt())),
 		},
 		gasPrice,
 		args.Roots,
-	}
+	}, nil
 }
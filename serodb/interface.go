@@ -16,6 +16,8 @@
 
 package serodb
 
+import "github.com/syndtr/goleveldb/leveldb/iterator"
+
 // Code using batches should try to add this much data to the batch.
 // The value was determined empirically.
 const IdealBatchSize = 100 * 1024
@@ -35,6 +37,18 @@ type Getter interface {
 	Has(key []byte) (bool, error)
 }
 
+// Iteratee wraps the prefix iteration supported by the leveldb and memory databases.
+type Iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Compacter wraps the range compaction supported by the leveldb database.
+type Compacter interface {
+	// Compact flattens the data store for the key range [start,limit),
+	// reclaiming the space of deleted entries.
+	Compact(start []byte, limit []byte) error
+}
+
 // Database wraps all database operations. All methods are safe for concurrent use.
 type Database interface {
 	Putter
@@ -17,10 +17,13 @@
 package serodb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/sero-cash/go-sero/common"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 /*
@@ -80,6 +83,23 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// NewIteratorWithPrefix returns an iterator over a snapshot of the entries
+// whose keys start with prefix, in the same byte-wise key order as the
+// leveldb backed database.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	entries := memEntries{}
+	for key, value := range db.db {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			entries = append(entries, kv{k: []byte(key), v: common.CopyBytes(value)})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].k, entries[j].k) < 0 })
+	return iterator.NewArrayIterator(entries)
+}
+
 func (db *MemDatabase) Delete(key []byte) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -101,6 +121,18 @@ type kv struct {
 	del  bool
 }
 
+// memEntries is a key ordered snapshot of the database, iterable as an
+// iterator.Array.
+type memEntries []kv
+
+func (e memEntries) Len() int { return len(e) }
+
+func (e memEntries) Search(key []byte) int {
+	return sort.Search(len(e), func(i int) bool { return bytes.Compare(e[i].k, key) >= 0 })
+}
+
+func (e memEntries) Index(i int) (key, value []byte) { return e[i].k, e[i].v }
+
 type memBatch struct {
 	db     *MemDatabase
 	writes []kv
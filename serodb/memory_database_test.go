@@ -0,0 +1,52 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package serodb
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMemDatabaseIteratorOrder(t *testing.T) {
+	db := NewMemDatabase()
+	pre := "BLOCK$"
+	// big endian numbers of varying width, inserted out of order
+	for _, num := range []uint64{256, 1, 65536, 2, 255} {
+		db.Put(append([]byte(pre), new(big.Int).SetUint64(num).Bytes()...), []byte{1})
+	}
+	db.Put([]byte("OTHER$"), []byte{1})
+	var keys []string
+	for _, k := range db.Keys() {
+		if strings.HasPrefix(string(k), pre) {
+			keys = append(keys, string(k))
+		}
+	}
+	sort.Strings(keys)
+
+	it := db.NewIteratorWithPrefix([]byte(pre))
+	defer it.Release()
+	var got []string
+	for it.Next() {
+		got = append(got, string(it.Key()))
+	}
+	if len(got) != 5 || !reflect.DeepEqual(got, keys) {
+		t.Fatalf("iterated keys out of order:\ngot  %x\nwant %x", got, keys)
+	}
+}
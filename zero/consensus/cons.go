@@ -471,8 +471,11 @@ func (self *Cons) Record(header *types.Header, batch DPutter) {
 
 	if len(recordlist) > 0 {
 		hash := header.Hash()
-		key:=DBObj{self.pre}.setBlockRecords(batch, header.Number.Uint64(), &hash, recordlist)
-		keys=append(keys,key)
+		recordKeys, err := DBObj{Pre: self.pre}.setBlockRecords(batch, header.Number.Uint64(), &hash, recordlist)
+		if err != nil {
+			panic(err)
+		}
+		keys=append(keys,recordKeys...)
 	}
 
 	dblist := self.fetchDBPairs()
@@ -159,7 +159,7 @@ func TestConsSetObj(t *testing.T) {
 func TestConsFetch(t *testing.T) {
 	db := NewFakeDB()
 	cmap := NewCons(&db, "block")
-	dbobj := DBObj{"treestate$"}
+	dbobj := DBObj{Pre: "treestate$"}
 	tree := NewObjPt(&cmap, "tree$", dbobj.Pre, "test")
 
 	cmap.CreateSnapshot(0)
@@ -210,9 +210,9 @@ func TestConsFetch(t *testing.T) {
 
 func TestConsRecord(t *testing.T) {
 	db := NewFakeDB()
-	dbcons := DBObj{"BLOCK$CONS$INDEX$"}
+	dbcons := DBObj{Pre: "BLOCK$CONS$INDEX$"}
 	cmap := NewCons(&db, dbcons.Pre)
-	dbobj := DBObj{"treestate$"}
+	dbobj := DBObj{Pre: "treestate$"}
 	tree := NewObjPt(&cmap, "tree$", dbobj.Pre, "test")
 
 	cmap.CreateSnapshot(0)
@@ -261,12 +261,25 @@ func TestConsRecord(t *testing.T) {
 	}
 
 	hash := header.Hash()
-	records := dbcons.GetBlockRecords(db.GlobalGetter(), 0, &hash)
+	records, err := dbcons.GetBlockRecords(db.GlobalGetter(), 0, &hash)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(records) != 1 && len(records[0].Pairs) != 2 {
 		t.FailNow()
 	}
 	fmt.Println(records)
 
+	// the hash index is listed with the block keys, so snapshots copy it
+	indexKey := makeHashIndexName(dbcons.Pre, &hash)
+	found := false
+	for _, k := range GetConsKeys(db.GlobalGetter(), 0, hash) {
+		found = found || bytes.Equal(k, indexKey)
+	}
+	if !found {
+		t.Error("hash index key missing from the cons keys")
+	}
+
 	cmap.Update()
 
 	cmap1 := NewCons(&db, dbcons.Pre)
@@ -285,10 +298,10 @@ func TestConsRecord(t *testing.T) {
 
 func TestConsRecord2(t *testing.T) {
 	db := NewFakeDB()
-	dbcons := DBObj{"BLOCK$CONS$INDEX$"}
+	dbcons := DBObj{Pre: "BLOCK$CONS$INDEX$"}
 	c := NewCons(&db, dbcons.Pre)
 	cmap := &c
-	dbobj := DBObj{"treestate$"}
+	dbobj := DBObj{Pre: "treestate$"}
 	tree := NewObjPt(cmap, "tree$", dbobj.Pre, "test")
 
 	cmap.CreateSnapshot(0)
@@ -374,7 +387,10 @@ func TestConsRecord2(t *testing.T) {
 	}
 
 	hash := header.Hash()
-	records := dbcons.GetBlockRecords(db.GlobalGetter(), 0, &hash)
+	records, err := dbcons.GetBlockRecords(db.GlobalGetter(), 0, &hash)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(records) != 1 && len(records[0].Pairs) != 3 {
 		t.FailNow()
 	}
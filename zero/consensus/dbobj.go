@@ -1,9 +1,18 @@
 package consensus
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
+	"runtime"
+	"sort"
+	"sync"
 
+	"github.com/golang/snappy"
 	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
 
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/serodb"
@@ -11,6 +20,32 @@ import (
 
 type DBObj struct {
 	Pre string
+
+	// Compressed makes setBlockRecords store the records snappy compressed.
+	// Records are read back either way, so it can be turned on for a
+	// database that already holds uncompressed ones.
+	Compressed bool
+}
+
+// recordsSnappy prefixes a snappy compressed records blob. Uncompressed blobs
+// are plain RLP lists, which start with a byte of at least 0xc0, so they are
+// stored without a prefix and read as before.
+const recordsSnappy = 0x01
+
+// encodeRecordsBlob returns the blob stored for the RLP encoded records.
+func (self DBObj) encodeRecordsBlob(enc []byte) []byte {
+	if !self.Compressed {
+		return enc
+	}
+	return append([]byte{recordsSnappy}, snappy.Encode(nil, enc)...)
+}
+
+// decodeRecordsBlob returns the RLP encoded records of a stored blob.
+func decodeRecordsBlob(blob []byte) ([]byte, error) {
+	if len(blob) == 0 || blob[0] != recordsSnappy {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob[1:])
 }
 
 func makeBlockName(pre string, num uint64, hash *common.Hash) (ret []byte) {
@@ -20,49 +55,526 @@ func makeBlockName(pre string, num uint64, hash *common.Hash) (ret []byte) {
 	return
 }
 
-func (self DBObj) setBlockRecords(batch serodb.Putter, num uint64, hash *common.Hash, records []*Record) (key []byte) {
-	if b, err := rlp.EncodeToBytes(&records); err != nil {
-		panic(err)
+func parseBlockName(pre string, name []byte) (num uint64, hash common.Hash, ok bool) {
+	if len(name) < len(pre)+common.HashLength || string(name[:len(pre)]) != pre {
+		return
+	}
+	numBytes := name[len(pre) : len(name)-common.HashLength]
+	if len(numBytes) > 8 {
+		return
+	}
+	num = new(big.Int).SetBytes(numBytes).Uint64()
+	copy(hash[:], name[len(name)-common.HashLength:])
+	ok = true
+	return
+}
+
+const hashIndexPre = "$CONS$HASH$INDEX$"
+
+// makeHashIndexName builds the key of the secondary hash->num index. It lives
+// outside of the records prefix so that scans over the records never see it.
+func makeHashIndexName(pre string, hash *common.Hash) (ret []byte) {
+	ret = []byte(hashIndexPre + pre)
+	ret = append(ret, hash[:]...)
+	return
+}
+
+func (self DBObj) setHashIndex(batch serodb.Putter, num uint64, hash *common.Hash) error {
+	return batch.Put(makeHashIndexName(self.Pre, hash), big.NewInt(int64(num)).Bytes())
+}
+
+// setBlockRecords stores the records of the block and its hash index entry in
+// the batch, returning the keys of both so the consensus can list them among
+// the keys written for the block.
+func (self DBObj) setBlockRecords(batch serodb.Putter, num uint64, hash *common.Hash, records []*Record) (keys [][]byte, err error) {
+	if b, e := rlp.EncodeToBytes(&records); e != nil {
+		err = fmt.Errorf("encode records of block %v(%v): %v", num, hash.Hex(), e)
+		return
 	} else {
 		name := makeBlockName(self.Pre, num, hash)
-		if err := batch.Put(name, b); err != nil {
-			panic(err)
+		if err = batch.Put(name, self.encodeRecordsBlob(b)); err != nil {
+			return
 		} else {
-			key=name
+			if err = self.setHashIndex(batch, num, hash); err != nil {
+				return
+			}
+			keys = [][]byte{name, makeHashIndexName(self.Pre, hash)}
 			return
 		}
 	}
 }
 
-func (self DBObj) GetBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash) (records []*Record) {
-	if b, err := getter.Get(makeBlockName(self.Pre, num, hash)); err != nil {
+const labelIndexPre = "$CONS$LABEL$INDEX$"
+
+// maxLabelLength bounds the labels of SetBlockRecordsLabeled, whose length is
+// stored in a single byte of the index key.
+const maxLabelLength = 255
+
+// makeLabelIndexName builds the key of the secondary label->num index. Like
+// the hash index it lives outside of the records prefix. The label is length
+// prefixed so that no label is a key prefix of another, and the number has a
+// fixed width so that the entries of a label are in height order.
+func makeLabelIndexName(pre string, label string, num uint64) (ret []byte) {
+	ret = makeLabelIndexPrefix(pre, label)
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], num)
+	ret = append(ret, enc[:]...)
+	return
+}
+
+func makeLabelIndexPrefix(pre string, label string) (ret []byte) {
+	ret = []byte(labelIndexPre + pre)
+	ret = append(ret, byte(len(label)))
+	ret = append(ret, label...)
+	return
+}
+
+func checkLabel(label string) error {
+	if len(label) == 0 || len(label) > maxLabelLength {
+		return fmt.Errorf("label %q: length must be between 1 and %d", label, maxLabelLength)
+	}
+	return nil
+}
+
+// SetBlockRecordsLabeled stores the records of a block like the consensus
+// does, and tags the block number with label so FindByLabel finds it.
+func (self DBObj) SetBlockRecordsLabeled(batch serodb.Putter, num uint64, hash *common.Hash, label string, records []*Record) error {
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	if _, err := self.setBlockRecords(batch, num, hash, records); err != nil {
+		return err
+	}
+	return batch.Put(makeLabelIndexName(self.Pre, label, num), nil)
+}
+
+// DeleteBlockRecordsLabeled removes what SetBlockRecordsLabeled stored: the
+// records of the block, their hash index entry and the label of the number.
+func (self DBObj) DeleteBlockRecordsLabeled(batch serodb.Deleter, num uint64, hash *common.Hash, label string) error {
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	if err := batch.Delete(makeBlockName(self.Pre, num, hash)); err != nil {
+		return err
+	}
+	if err := batch.Delete(makeHashIndexName(self.Pre, hash)); err != nil {
+		return err
+	}
+	return batch.Delete(makeLabelIndexName(self.Pre, label, num))
+}
+
+// FindByLabel returns the block numbers tagged with label, in ascending
+// order. Only the label index is scanned, the records are never read.
+func (self DBObj) FindByLabel(iter serodb.Iteratee, label string) ([]uint64, error) {
+	if err := checkLabel(label); err != nil {
+		return nil, err
+	}
+	prefix := makeLabelIndexPrefix(self.Pre, label)
+	it := iter.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var nums []uint64
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		nums = append(nums, binary.BigEndian.Uint64(it.Key()[len(prefix):]))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return nums, nil
+}
+
+func (self DBObj) GetBlockNum(getter serodb.Getter, hash *common.Hash) (num uint64, ok bool) {
+	if v, err := getter.Get(makeHashIndexName(self.Pre, hash)); err != nil {
 		return
 	} else {
-		if err := rlp.DecodeBytes(b, &records); err != nil {
-			panic(err)
+		return new(big.Int).SetBytes(v).Uint64(), true
+	}
+}
+
+// RebuildHashIndex walks all the stored block records and rewrites the
+// secondary hash->num index in bounded batches. Existing entries are simply
+// overwritten, so it is safe to run repeatedly and on a live database.
+func (self DBObj) RebuildHashIndex(db serodb.Database) (int, error) {
+	iteratee, ok := db.(serodb.Iteratee)
+	if !ok {
+		return 0, errors.New("rebuild hash index: database can not be iterated")
+	}
+	it := iteratee.NewIteratorWithPrefix([]byte(self.Pre))
+	defer it.Release()
+
+	count := 0
+	batch := db.NewBatch()
+	for it.Next() {
+		num, hash, ok := parseBlockName(self.Pre, it.Key())
+		if !ok {
+			continue
+		}
+		if err := self.setHashIndex(batch, num, &hash); err != nil {
+			return count, err
+		}
+		count++
+		if batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return count, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return count, err
+	}
+	if err := batch.Write(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// GetBlockRecords returns the records stored for the block, none if there are
+// none, or an error if they can not be read or decoded.
+func (self DBObj) GetBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash) (records []*Record, err error) {
+	name := makeBlockName(self.Pre, num, hash)
+	if b, e := getter.Get(name); e != nil {
+		if has, he := getter.Has(name); he == nil && !has {
+			return
+		}
+		return nil, fmt.Errorf("read records of block %v(%v): %v", num, hash.Hex(), e)
+	} else {
+		if b, e = decodeRecordsBlob(b); e != nil {
+			return nil, fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), e)
+		}
+		if e := rlp.DecodeBytes(b, &records); e != nil {
+			return nil, fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), e)
 		} else {
 			return
 		}
 	}
 }
 
-func (self DBObj) GetBlockRecordsMap(getter serodb.Getter, num uint64, hash *common.Hash) (records map[string][]RecordPair) {
+// HasBlockRecords reports whether at least one record is stored for the
+// block. Only the list prefix of the stored records is read, none of them is
+// decoded.
+func (self DBObj) HasBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash) bool {
+	b, err := getter.Get(makeBlockName(self.Pre, num, hash))
+	if err != nil {
+		return false
+	}
+	if b, err = decodeRecordsBlob(b); err != nil {
+		return false
+	}
+	content, _, err := rlp.SplitList(b)
+	return err == nil && len(content) > 0
+}
+
+// DecodeRecordsHeader reads the RLP list prefix of a records blob as stored by
+// setBlockRecords, returning the number of records, the size of the list
+// payload and the size of the prefix itself. The records are only skipped
+// over, never decoded, but the blob must hold exactly one well framed list.
+// A compressed blob is decompressed first and the sizes are those of the
+// uncompressed list.
+func DecodeRecordsHeader(raw []byte) (count int, payloadLen int, headerLen int, err error) {
+	if raw, err = decodeRecordsBlob(raw); err != nil {
+		return
+	}
+	content, rest, e := rlp.SplitList(raw)
+	if e != nil {
+		err = e
+		return
+	}
+	if len(rest) != 0 {
+		err = fmt.Errorf("records blob has %d trailing bytes", len(rest))
+		return
+	}
+	if count, err = rlp.CountValues(content); err != nil {
+		return
+	}
+	payloadLen = len(content)
+	headerLen = len(raw) - len(content)
+	return
+}
+
+// RecordsRoot commits to a block's records as the keccak256 hash of their
+// canonical RLP encoding, the same encoding setBlockRecords stores.
+func RecordsRoot(records []*Record) (root common.Hash, err error) {
+	if b, e := rlp.EncodeToBytes(&records); e != nil {
+		err = e
+		return
+	} else {
+		root = crypto.Keccak256Hash(b)
+		return
+	}
+}
+
+// VerifyRecordsRoot recomputes the root of the records stored for the block
+// and reports whether it matches the claimed one.
+func (self DBObj) VerifyRecordsRoot(getter serodb.Getter, num uint64, hash *common.Hash, claimed common.Hash) (bool, error) {
+	b, err := getter.Get(makeBlockName(self.Pre, num, hash))
+	if err != nil {
+		return false, fmt.Errorf("no records for block %v(%v): %v", num, hash.Hex(), err)
+	}
+	if b, err = decodeRecordsBlob(b); err != nil {
+		return false, fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+	}
+	var records []*Record
+	if err := rlp.DecodeBytes(b, &records); err != nil {
+		return false, fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+	}
+	root, err := RecordsRoot(records)
+	if err != nil {
+		return false, err
+	}
+	return root == claimed, nil
+}
+
+// GetBlockRecordsMulti loads the records of several blocks at once, fetching
+// them concurrently with at most runtime.NumCPU reads in flight. The getter
+// must be safe for concurrent use, as databases are. The returned records and
+// errors are aligned with keys; the records are nil where the error is not.
+func (self DBObj) GetBlockRecordsMulti(getter serodb.Getter, keys []struct {
+	Num  uint64
+	Hash common.Hash
+}) ([][]*Record, []error) {
+	records := make([][]*Record, len(keys))
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			num, hash := keys[i].Num, keys[i].Hash
+			b, err := getter.Get(makeBlockName(self.Pre, num, &hash))
+			if err != nil {
+				errs[i] = fmt.Errorf("no records for block %v(%v): %v", num, hash.Hex(), err)
+				return
+			}
+			if b, err = decodeRecordsBlob(b); err != nil {
+				errs[i] = fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+				return
+			}
+			if err := rlp.DecodeBytes(b, &records[i]); err != nil {
+				records[i] = nil
+				errs[i] = fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return records, errs
+}
+
+// IterateBlockRecords decodes the records of a block one at a time, calling
+// fn with each until it returns false. Unlike GetBlockRecords, undecodable
+// records are reported as an error. A block without records yields none.
+func (self DBObj) IterateBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash, fn func(*Record) bool) error {
+	b, err := getter.Get(makeBlockName(self.Pre, num, hash))
+	if err != nil {
+		return nil
+	}
+	if b, err = decodeRecordsBlob(b); err != nil {
+		return fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+	}
+	s := rlp.NewStream(bytes.NewReader(b), uint64(len(b)))
+	if _, err := s.List(); err != nil {
+		return fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+	}
+	for {
+		record := new(Record)
+		if err := s.Decode(record); err == rlp.EOL {
+			break
+		} else if err != nil {
+			return fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+		}
+		if !fn(record) {
+			return nil
+		}
+	}
+	if err := s.ListEnd(); err != nil {
+		return fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+	}
+	return nil
+}
+
+// QueryRecords decodes the records of a block one at a time and returns the
+// ones filter accepts, so the rejected records are never kept. A block
+// without records yields none.
+func (self DBObj) QueryRecords(getter serodb.Getter, num uint64, hash *common.Hash, filter func(*Record) bool) ([]*Record, error) {
+	var records []*Record
+	err := self.IterateBlockRecords(getter, num, hash, func(record *Record) bool {
+		if filter(record) {
+			records = append(records, record)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (self DBObj) GetBlockRecordsMap(getter serodb.Getter, num uint64, hash *common.Hash) (records map[string][]RecordPair, err error) {
+	rds, err := self.GetBlockRecords(getter, num, hash)
+	if err != nil {
+		return nil, err
+	}
 	records = make(map[string][]RecordPair)
-	rds := self.GetBlockRecords(getter, num, hash)
 	for _, v := range rds {
 		records[v.Name] = v.Pairs
 	}
 	return
 }
 
-func (self DBObj) GetObject(getter serodb.Getter, hash []byte, item CItem) (ret CItem) {
+// GetBlockRecordsByValue is GetBlockRecords taking the block hash by value,
+// for callers holding a hash that isn't addressable.
+func (self DBObj) GetBlockRecordsByValue(getter serodb.Getter, num uint64, hash common.Hash) (records []*Record, err error) {
+	return self.GetBlockRecords(getter, num, &hash)
+}
+
+// GetBlockRecordsMapByValue is GetBlockRecordsMap taking the block hash by
+// value, for callers holding a hash that isn't addressable.
+func (self DBObj) GetBlockRecordsMapByValue(getter serodb.Getter, num uint64, hash common.Hash) (records map[string][]RecordPair, err error) {
+	return self.GetBlockRecordsMap(getter, num, &hash)
+}
+
+// SwapBlockRecords exchanges the records stored for two blocks at the same
+// height. Both writes go through a single batch so readers never observe a
+// state where the two hashes share or lack records.
+func (self DBObj) SwapBlockRecords(db serodb.Database, num uint64, a, b *common.Hash) error {
+	nameA := makeBlockName(self.Pre, num, a)
+	nameB := makeBlockName(self.Pre, num, b)
+	valueA, err := db.Get(nameA)
+	if err != nil {
+		return fmt.Errorf("swap block records: records of %v at %v not found", a.Hex(), num)
+	}
+	valueB, err := db.Get(nameB)
+	if err != nil {
+		return fmt.Errorf("swap block records: records of %v at %v not found", b.Hex(), num)
+	}
+	batch := db.NewBatch()
+	if err := batch.Put(nameA, valueB); err != nil {
+		return err
+	}
+	if err := batch.Put(nameB, valueA); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// ErrObjectNotFound is returned by FetchObject when no object is stored
+// under the hash.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectDecodeError is returned by FetchObject when the stored object can not
+// be decoded, which means the data is corrupt.
+type ObjectDecodeError struct {
+	Hash []byte
+	Err  error
+}
+
+func (e *ObjectDecodeError) Error() string {
+	return fmt.Sprintf("decode object %x: %v", e.Hash, e.Err)
+}
+
+// FetchObject decodes the object stored under the hash into item. Unlike
+// GetObject it tells a missing object, reported as ErrObjectNotFound, from a
+// corrupt one, reported as an *ObjectDecodeError.
+func (self DBObj) FetchObject(getter serodb.Getter, hash []byte, item CItem) (CItem, error) {
 	k := key{self.Pre, hash}
-	if v, err := getter.Get([]byte(k.k())); err != nil {
-		return
+	v, err := getter.Get([]byte(k.k()))
+	if err != nil {
+		return nil, ErrObjectNotFound
+	}
+	if e := rlp.DecodeBytes(v, item); e != nil {
+		return nil, &ObjectDecodeError{common.CopyBytes(hash), e}
+	}
+	return item, nil
+}
+
+// GetObject decodes the object stored under the hash into item, returning nil
+// if it is missing or corrupt.
+func (self DBObj) GetObject(getter serodb.Getter, hash []byte, item CItem) (ret CItem) {
+	if item, err := self.FetchObject(getter, hash, item); err == nil {
+		return item
+	}
+	return nil
+}
+
+// PutObjects writes the items into the batch under their hashes, the keys of
+// the map, so GetObject reads them back. All of them are encoded before the
+// first write, so an item that can not be encoded leaves the batch untouched.
+// The items are written in hash order and a failing write stops the rest.
+func (self DBObj) PutObjects(batch serodb.Putter, items map[string]CItem) error {
+	hashes := make([]string, 0, len(items))
+	for hash := range items {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	values := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		if items[hash] == nil {
+			return fmt.Errorf("encode object %x: nil item", hash)
+		}
+		b, err := rlp.EncodeToBytes(items[hash])
+		if err != nil {
+			return fmt.Errorf("encode object %x: %v", hash, err)
+		}
+		values[i] = b
+	}
+	for i, hash := range hashes {
+		k := key{self.Pre, []byte(hash)}
+		if err := batch.Put([]byte(k.k()), values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountPairs returns the number of record pairs stored for the block. A block
+// without stored records counts 0, a failure to read or decode them is
+// returned as an error.
+func (self DBObj) CountPairs(getter serodb.Getter, num uint64, hash *common.Hash) (int, error) {
+	name := makeBlockName(self.Pre, num, hash)
+	if b, err := getter.Get(name); err != nil {
+		if has, e := getter.Has(name); e == nil && !has {
+			return 0, nil
+		}
+		return 0, err
 	} else {
-		if e := rlp.DecodeBytes(v, item); e != nil {
-			return nil
+		if b, err = decodeRecordsBlob(b); err != nil {
+			return 0, err
 		}
-		return item
+		var records []*Record
+		if err := rlp.DecodeBytes(b, &records); err != nil {
+			return 0, err
+		}
+		count := 0
+		for _, r := range records {
+			count += len(r.Pairs)
+		}
+		return count, nil
+	}
+}
+
+// GetObjectOrEvict works like GetObject, but an entry that fails to decode is
+// deleted and the decode error returned, telling the caller to re-derive and
+// store the object again. A missing object returns (nil, false, nil).
+func (self DBObj) GetObjectOrEvict(db serodb.Database, hash []byte, item CItem) (CItem, bool, error) {
+	name := key{self.Pre, hash}
+	k := []byte(name.k())
+	v, err := db.Get(k)
+	if err != nil {
+		return nil, false, nil
+	}
+	if e := rlp.DecodeBytes(v, item); e != nil {
+		if err := db.Delete(k); err != nil {
+			return nil, false, fmt.Errorf("evict corrupt object %x: %v (decode: %v)", hash, err, e)
+		}
+		return nil, false, e
 	}
+	return item, true, nil
 }
@@ -0,0 +1,792 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func testRecords(name string, n int) (ret []*Record) {
+	r := &Record{Name: name}
+	for i := 0; i < n; i++ {
+		r.Pairs = append(r.Pairs, RecordPair{Ref: []byte{byte(i)}, Hash: []byte{byte(i), 1}})
+	}
+	return append(ret, r)
+}
+
+func TestDBObjRebuildHashIndex(t *testing.T) {
+	db := serodb.NewMemDatabase()
+
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hashes := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x0300")}
+	for i := range hashes {
+		dbobj.setBlockRecords(db, uint64(i*1000), &hashes[i], testRecords("test", i+1))
+		if err := db.Delete(makeHashIndexName(dbobj.Pre, &hashes[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := dbobj.GetBlockNum(db, &hashes[0]); ok {
+		t.Fatal("index should be empty")
+	}
+	for round := 0; round < 2; round++ {
+		count, err := dbobj.RebuildHashIndex(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(hashes) {
+			t.Fatalf("rebuilt %d entries, want %d", count, len(hashes))
+		}
+		for i := range hashes {
+			if num, ok := dbobj.GetBlockNum(db, &hashes[i]); !ok || num != uint64(i*1000) {
+				t.Fatalf("hash %v: got num %v (%v), want %v", hashes[i].Hex(), num, ok, i*1000)
+			}
+		}
+	}
+}
+
+func TestDBObjExportJSONL(t *testing.T) {
+	db := serodb.NewMemDatabase()
+
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	for i := uint64(1); i <= 4; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		dbobj.setBlockRecords(db, i, &hash, testRecords("test", 1))
+	}
+	var buf bytes.Buffer
+	count, err := dbobj.ExportJSONL(context.Background(), db, 2, 3, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("exported %d blocks, want 2", count)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], `"pairs":[{"ref":"0x00","hash":"0x0001"}]`) {
+		t.Errorf("unexpected line %s", lines[0])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dbobj.ExportJSONL(ctx, db, 0, 10, &buf); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDBObjSwapBlockRecords(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	a, b, c := common.HexToHash("0x0a"), common.HexToHash("0x0b"), common.HexToHash("0x0c")
+	dbobj.setBlockRecords(db, 7, &a, testRecords("a", 1))
+	dbobj.setBlockRecords(db, 7, &b, testRecords("b", 2))
+
+	if err := dbobj.SwapBlockRecords(db, 7, &a, &b); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := dbobj.GetBlockRecords(db, 7, &a); len(records) != 1 || records[0].Name != "b" {
+		t.Errorf("records of a not swapped: %v", records)
+	}
+	if records, _ := dbobj.GetBlockRecords(db, 7, &b); len(records) != 1 || records[0].Name != "a" {
+		t.Errorf("records of b not swapped: %v", records)
+	}
+	if err := dbobj.SwapBlockRecords(db, 7, &a, &c); err == nil {
+		t.Error("expected error for missing records")
+	}
+}
+
+func TestDBObjRangeSize(t *testing.T) {
+	db := serodb.NewMemDatabase()
+
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	want := uint64(0)
+	for i := uint64(0); i < 300; i += 50 {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		records := testRecords("test", int(i/50)+1)
+		dbobj.setBlockRecords(db, i, &hash, records)
+		if i >= 100 && i <= 250 {
+			b, _ := rlp.EncodeToBytes(&records)
+			want += uint64(len(b))
+		}
+	}
+	size, err := dbobj.RangeSize(context.Background(), db, 100, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want {
+		t.Fatalf("got size %d, want %d", size, want)
+	}
+}
+
+func TestDBObjGetObjectOrEvict(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "treestate$"}
+	obj := NewTestObj2("obj0", "0")
+	b, _ := rlp.EncodeToBytes(obj)
+	good, bad := key{dbobj.Pre, []byte("good")}, key{dbobj.Pre, []byte("bad")}
+	db.Put([]byte(good.k()), b)
+	db.Put([]byte(bad.k()), []byte{0xff, 0x01})
+
+	if item, ok, err := dbobj.GetObjectOrEvict(db, []byte("good"), &TestObj{}); err != nil || !ok || item.(*TestObj).I != "obj0" {
+		t.Fatalf("good object: %v %v %v", item, ok, err)
+	}
+	if item, ok, err := dbobj.GetObjectOrEvict(db, []byte("none"), &TestObj{}); err != nil || ok || item != nil {
+		t.Fatalf("missing object: %v %v %v", item, ok, err)
+	}
+	if item, ok, err := dbobj.GetObjectOrEvict(db, []byte("bad"), &TestObj{}); err == nil || ok || item != nil {
+		t.Fatalf("corrupt object: %v %v %v", item, ok, err)
+	}
+	if has, _ := db.Has([]byte(bad.k())); has {
+		t.Fatal("corrupt object not evicted")
+	}
+}
+
+func TestDBObjFetchObject(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "treestate$"}
+	b, _ := rlp.EncodeToBytes(NewTestObj2("obj0", "0"))
+	good, bad := key{dbobj.Pre, []byte("good")}, key{dbobj.Pre, []byte("bad")}
+	db.Put([]byte(good.k()), b)
+	db.Put([]byte(bad.k()), []byte{0xff, 0x01})
+
+	if item, err := dbobj.FetchObject(db, []byte("good"), &TestObj{}); err != nil || item.(*TestObj).I != "obj0" {
+		t.Fatalf("good object: %v %v", item, err)
+	}
+	if item, err := dbobj.FetchObject(db, []byte("none"), &TestObj{}); err != ErrObjectNotFound || item != nil {
+		t.Fatalf("missing object: have %v %v, want ErrObjectNotFound", item, err)
+	}
+	item, err := dbobj.FetchObject(db, []byte("bad"), &TestObj{})
+	if decodeErr, ok := err.(*ObjectDecodeError); !ok || string(decodeErr.Hash) != "bad" || item != nil {
+		t.Fatalf("corrupt object: have %v %v, want an ObjectDecodeError", item, err)
+	}
+	if item := dbobj.GetObject(db, []byte("bad"), &TestObj{}); item != nil {
+		t.Fatalf("GetObject of a corrupt object: have %v, want nil", item)
+	}
+}
+
+func TestDBObjPutObjects(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "treestate$"}
+	items := make(map[string]CItem)
+	for i := 0; i < 10; i++ {
+		items[fmt.Sprintf("obj%d", i)] = NewTestObj2(fmt.Sprintf("obj%d", i), fmt.Sprint(i))
+	}
+	batch := db.NewBatch()
+	if err := dbobj.PutObjects(batch, items); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	for hash, want := range items {
+		item := dbobj.GetObject(db, []byte(hash), &TestObj{})
+		if item == nil || *item.(*TestObj) != *want.(*TestObj) {
+			t.Errorf("%s: have %v, want %v", hash, item, want)
+		}
+	}
+
+	items["bad"] = nil
+	batch = db.NewBatch()
+	if err := dbobj.PutObjects(batch, items); err == nil {
+		t.Fatal("expected error for an unencodable item")
+	}
+	if batch.ValueSize() != 0 {
+		t.Errorf("batch holds %d bytes after a failed encode", batch.ValueSize())
+	}
+}
+
+func TestDBObjHasBlockRecords(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	full, empty, none := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+	dbobj.setBlockRecords(db, 1, &full, testRecords("test", 2))
+	dbobj.setBlockRecords(db, 2, &empty, nil)
+
+	if !dbobj.HasBlockRecords(db, 1, &full) {
+		t.Error("block with records: have none")
+	}
+	if dbobj.HasBlockRecords(db, 2, &empty) {
+		t.Error("block stored without records: have some")
+	}
+	if dbobj.HasBlockRecords(db, 3, &none) {
+		t.Error("missing block: have records")
+	}
+	if dbobj.HasBlockRecords(db, 2, &full) {
+		t.Error("block with records under another number: have records")
+	}
+}
+
+func TestDecodeRecordsHeader(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 200} {
+		records := []*Record{}
+		for i := 0; i < n; i++ {
+			records = append(records, testRecords("test", i%4)...)
+		}
+		raw, _ := rlp.EncodeToBytes(records)
+		count, payloadLen, headerLen, err := DecodeRecordsHeader(raw)
+		if err != nil {
+			t.Fatalf("%d records: %v", n, err)
+		}
+		if count != n || payloadLen+headerLen != len(raw) {
+			t.Errorf("%d records: have count %d, payload %d, header %d for %d bytes", n, count, payloadLen, headerLen, len(raw))
+		}
+	}
+	raw, _ := rlp.EncodeToBytes(testRecords("test", 2))
+	if _, _, _, err := DecodeRecordsHeader(append(raw, 0x80)); err == nil {
+		t.Error("expected error for trailing bytes")
+	}
+	if _, _, _, err := DecodeRecordsHeader(raw[:len(raw)-1]); err == nil {
+		t.Error("expected error for truncated blob")
+	}
+	if _, _, _, err := DecodeRecordsHeader([]byte{0x83, 'a', 'b', 'c'}); err == nil {
+		t.Error("expected error for non-list blob")
+	}
+}
+
+func TestDBObjVerifyRecordsRoot(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hash := common.HexToHash("0x01")
+	records := append(testRecords("a", 2), testRecords("b", 1)...)
+	dbobj.setBlockRecords(db, 5, &hash, records)
+
+	root, err := RecordsRoot(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := dbobj.VerifyRecordsRoot(db, 5, &hash, root); err != nil || !ok {
+		t.Errorf("matching root: %v %v", ok, err)
+	}
+	other, _ := RecordsRoot(testRecords("a", 2))
+	if ok, err := dbobj.VerifyRecordsRoot(db, 5, &hash, other); err != nil || ok {
+		t.Errorf("diverging root: %v %v", ok, err)
+	}
+	if _, err := dbobj.VerifyRecordsRoot(db, 6, &hash, root); err == nil {
+		t.Error("expected error for missing records")
+	}
+}
+
+func TestDBObjDeleteRange(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	for i := uint64(0); i < 3000; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		dbobj.setBlockRecords(db, i, &hash, testRecords("test", 1))
+	}
+	count, err := dbobj.DeleteRange(context.Background(), db, 100, 2599)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2500 {
+		t.Fatalf("deleted %d blocks, want 2500", count)
+	}
+	for _, i := range []uint64{0, 99, 100, 2599, 2600, 2999} {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		_, indexed := dbobj.GetBlockNum(db, &hash)
+		records, _ := dbobj.GetBlockRecords(db, i, &hash)
+		stored := len(records) > 0
+		if want := i < 100 || i > 2599; stored != want || indexed != want {
+			t.Errorf("block %d: stored %v, indexed %v, want %v", i, stored, indexed, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if count, err := dbobj.DeleteRange(ctx, db, 0, 3000); err != context.Canceled || count != 0 {
+		t.Errorf("cancelled: have %d %v, want 0 %v", count, err, context.Canceled)
+	}
+}
+
+// objectHash is the 32 byte hash an object with the one letter id is stored
+// under in the iteration tests.
+func objectHash(id string) []byte {
+	return common.BytesToHash([]byte(id)).Bytes()
+}
+
+func TestDBObjIterateObjects(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "treestate$"}
+	for _, id := range []string{"b", "a", "c"} {
+		b, _ := rlp.EncodeToBytes(NewTestObj2("obj"+id, id))
+		k := key{dbobj.Pre, objectHash(id)}
+		db.Put([]byte(k.k()), b)
+	}
+	db.Put([]byte("other$x"), []byte{0xff})
+	// a table whose prefix starts with the one of the objects
+	db.Put(append([]byte(dbobj.Pre+"NUM$"), objectHash("d")...), []byte{0xff})
+
+	var ids []string
+	err := dbobj.IterateObjects(db, func() CItem { return &TestObj{} }, func(hash []byte, item CItem) error {
+		id := string(hash[len(hash)-1:])
+		if obj := item.(*TestObj); obj.I != "obj"+id {
+			t.Errorf("object %s: have %v", id, obj)
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("have %v, want a,b,c", ids)
+	}
+
+	db.Put(append([]byte(dbobj.Pre), objectHash("e")...), []byte{0xff, 0x01})
+	if err := dbobj.IterateObjects(db, func() CItem { return &TestObj{} }, func([]byte, CItem) error { return nil }); err == nil {
+		t.Error("expected error for undecodable object")
+	}
+}
+
+func TestDBObjIterateRawObjects(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "treestate$"}
+	stored := make(map[string][]byte)
+	for _, id := range []string{"b", "a", "c"} {
+		b, _ := rlp.EncodeToBytes(NewTestObj2("obj"+id, id))
+		k := key{dbobj.Pre, objectHash(id)}
+		db.Put([]byte(k.k()), b)
+		stored[id] = b
+	}
+	db.Put([]byte("other$x"), []byte{0xff})
+	db.Put(append([]byte(dbobj.Pre+"NUM$"), objectHash("d")...), []byte{0xff})
+
+	var ids []string
+	err := dbobj.IterateRawObjects(db, func(hash []byte, raw []byte) bool {
+		id := string(hash[len(hash)-1:])
+		if !bytes.Equal(raw, stored[id]) {
+			t.Errorf("object %s: have %x, want %x", id, raw, stored[id])
+		}
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("have %v, want a,b,c", ids)
+	}
+
+	ids = nil
+	dbobj.IterateRawObjects(db, func(hash []byte, raw []byte) bool {
+		ids = append(ids, string(hash[len(hash)-1:]))
+		return len(ids) < 2
+	})
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("have %v, want the walk to stop after a,b", ids)
+	}
+}
+
+func TestDBObjHealthCheck(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	for i := uint64(1); i <= 5; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		dbobj.setBlockRecords(db, i, &hash, testRecords("test", 1))
+	}
+	for _, i := range []uint64{2, 4} {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		db.Put(makeBlockName(dbobj.Pre, i, &hash), []byte{0xff, 0x01})
+	}
+	issues, err := dbobj.HealthCheck(context.Background(), db, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Num != 2 || issues[0].Hash != common.BigToHash(big.NewInt(2)) || issues[0].Err == nil {
+		t.Fatalf("have issues %v, want block 2", issues)
+	}
+	if issues, _ := dbobj.HealthCheck(context.Background(), db, 0, 10); len(issues) != 2 {
+		t.Fatalf("have %d issues, want 2", len(issues))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dbobj.HealthCheck(ctx, db, 0, 10); err != context.Canceled {
+		t.Errorf("have %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDBObjGetBlockRecordsMulti(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	var keys []struct {
+		Num  uint64
+		Hash common.Hash
+	}
+	for i := uint64(1); i <= 4; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		if i != 3 {
+			dbobj.setBlockRecords(db, i, &hash, testRecords("test", int(i)))
+		}
+		keys = append(keys, struct {
+			Num  uint64
+			Hash common.Hash
+		}{i, hash})
+	}
+	// a repeated key gets its own result
+	keys = append(keys, keys[1])
+	records, errs := dbobj.GetBlockRecordsMulti(db, keys)
+	if len(records) != len(keys) || len(errs) != len(keys) {
+		t.Fatalf("have %d records and %d errors, want %d", len(records), len(errs), len(keys))
+	}
+	for i, key := range keys {
+		if key.Num == 3 {
+			if errs[i] == nil {
+				t.Error("expected error for missing block 3")
+			}
+			if records[i] != nil {
+				t.Errorf("missing block 3: have %v", records[i])
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("block %d: %v", key.Num, errs[i])
+		} else if rs := records[i]; len(rs) != 1 || len(rs[0].Pairs) != int(key.Num) {
+			t.Errorf("block %d: have %v", key.Num, rs)
+		}
+	}
+}
+
+func TestDBObjMaxBlockNumber(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	if _, ok, err := dbobj.MaxBlockNumber(db); ok || err != nil {
+		t.Fatalf("empty database: have ok %v, err %v", ok, err)
+	}
+	// 0xff sorts after 0x0100 but is the lower block.
+	for _, num := range []uint64{3, 0x0100, 0xff, 7} {
+		hash := common.BigToHash(new(big.Int).SetUint64(num))
+		dbobj.setBlockRecords(db, num, &hash, testRecords("test", 1))
+	}
+	if max, ok, err := dbobj.MaxBlockNumber(db); !ok || err != nil || max != 0x0100 {
+		t.Fatalf("have %d %v %v, want %d", max, ok, err, 0x0100)
+	}
+}
+
+func TestDBObjQueryRecords(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hash := common.HexToHash("0x01")
+	records := append(testRecords("a", 1), append(testRecords("b", 2), testRecords("a", 3)...)...)
+	dbobj.setBlockRecords(db, 1, &hash, records)
+
+	found, err := dbobj.QueryRecords(db, 1, &hash, func(r *Record) bool { return r.Name == "a" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || len(found[0].Pairs) != 1 || len(found[1].Pairs) != 3 {
+		t.Fatalf("have %v, want the two records named a", found)
+	}
+	missing := common.HexToHash("0x02")
+	if found, err := dbobj.QueryRecords(db, 1, &missing, func(*Record) bool { return true }); found != nil || err != nil {
+		t.Errorf("missing block: have %v %v", found, err)
+	}
+	db.Put(makeBlockName(dbobj.Pre, 1, &hash), []byte{0xc2, 0x01})
+	if _, err := dbobj.QueryRecords(db, 1, &hash, func(*Record) bool { return true }); err == nil {
+		t.Error("expected error for undecodable records")
+	}
+}
+
+func TestDBObjIterateBlockRecords(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hash := common.HexToHash("0x01")
+	records := append(testRecords("a", 1), append(testRecords("b", 2), testRecords("c", 3)...)...)
+	dbobj.setBlockRecords(db, 1, &hash, records)
+
+	var names []string
+	err := dbobj.IterateBlockRecords(db, 1, &hash, func(r *Record) bool {
+		names = append(names, r.Name)
+		return r.Name != "b"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("have %v, want iteration to stop after b", names)
+	}
+	db.Put(makeBlockName(dbobj.Pre, 1, &hash), []byte{0xc2, 0x01})
+	if err := dbobj.IterateBlockRecords(db, 1, &hash, func(*Record) bool { return true }); err == nil {
+		t.Error("expected error for undecodable records")
+	}
+}
+
+type compactingDB struct {
+	*serodb.MemDatabase
+	ranges [][2][]byte
+}
+
+func (db *compactingDB) Compact(start []byte, limit []byte) error {
+	db.ranges = append(db.ranges, [2][]byte{start, limit})
+	return nil
+}
+
+func TestDBObjCompactKeyRange(t *testing.T) {
+	db := &compactingDB{MemDatabase: serodb.NewMemDatabase()}
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	if err := dbobj.CompactKeyRange(db, 0xf0, 0x0110); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.ranges) != 2 {
+		t.Fatalf("have %d compacted ranges, want one per key width", len(db.ranges))
+	}
+	covered := func(key []byte) bool {
+		for _, r := range db.ranges {
+			if bytes.Compare(key, r[0]) >= 0 && bytes.Compare(key, r[1]) < 0 {
+				return true
+			}
+		}
+		return false
+	}
+	max := common.BytesToHash(bytes.Repeat([]byte{0xff}, common.HashLength))
+	for _, num := range []uint64{0xf0, 0xff, 0x0100, 0x0110} {
+		for _, hash := range []common.Hash{{}, max} {
+			if key := makeBlockName(dbobj.Pre, num, &hash); !covered(key) {
+				t.Errorf("block %d key %x not compacted", num, key)
+			}
+		}
+	}
+	// backends without compaction support are left alone
+	if err := dbobj.CompactKeyRange(serodb.NewMemDatabase(), 0, 10); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type failingDB struct {
+	*serodb.MemDatabase
+}
+
+var errFailingDB = errors.New("failing database")
+
+func (db failingDB) Put(key []byte, value []byte) error {
+	return errFailingDB
+}
+
+// unreadableDB holds its keys but fails to read them.
+type unreadableDB struct {
+	*serodb.MemDatabase
+}
+
+func (db unreadableDB) Get(key []byte) ([]byte, error) {
+	return nil, errFailingDB
+}
+
+func TestDBObjCountPairs(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hash, missing := common.HexToHash("0x01"), common.HexToHash("0x02")
+	dbobj.setBlockRecords(db, 1, &hash, append(testRecords("a", 2), testRecords("b", 3)...))
+
+	if n, err := dbobj.CountPairs(db, 1, &hash); err != nil || n != 5 {
+		t.Errorf("have %d %v, want 5", n, err)
+	}
+	if n, err := dbobj.CountPairs(db, 2, &missing); err != nil || n != 0 {
+		t.Errorf("missing block: have %d %v, want 0", n, err)
+	}
+	if _, err := dbobj.CountPairs(unreadableDB{db}, 1, &hash); err != errFailingDB {
+		t.Errorf("unreadable block: have %v, want %v", err, errFailingDB)
+	}
+}
+
+func TestDBObjRecordsErrors(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hash := common.HexToHash("0x01")
+
+	if _, err := dbobj.setBlockRecords(failingDB{db}, 1, &hash, testRecords("test", 1)); err != errFailingDB {
+		t.Fatalf("have %v, want %v", err, errFailingDB)
+	}
+	if records, err := dbobj.GetBlockRecords(db, 1, &hash); records != nil || err != nil {
+		t.Fatalf("missing block: have %v %v", records, err)
+	}
+	dbobj.setBlockRecords(db, 1, &hash, testRecords("test", 1))
+	if records, err := dbobj.GetBlockRecords(unreadableDB{db}, 1, &hash); records != nil || err == nil || !strings.Contains(err.Error(), errFailingDB.Error()) {
+		t.Fatalf("unreadable block: have %v %v", records, err)
+	}
+	db.Put(makeBlockName(dbobj.Pre, 1, &hash), []byte{0xc2, 0x01})
+	if _, err := dbobj.GetBlockRecords(db, 1, &hash); err == nil {
+		t.Error("expected error for undecodable records")
+	}
+	if _, err := dbobj.GetBlockRecordsMap(db, 1, &hash); err == nil {
+		t.Error("expected error for undecodable records map")
+	}
+}
+
+func TestDBObjDeleteBlockRange(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hashes := make(map[uint64]*common.Hash)
+	for i := uint64(1); i <= 5; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		hashes[i] = &hash
+		if i != 3 {
+			dbobj.setBlockRecords(db, i, &hash, testRecords("test", 1))
+		}
+	}
+	batch := db.NewBatch()
+	if err := dbobj.DeleteBlockRange(batch, 2, 4, hashes); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 5; i++ {
+		records, _ := dbobj.GetBlockRecords(db, i, hashes[i])
+		_, indexed := dbobj.GetBlockNum(db, hashes[i])
+		if want := i == 1 || i == 5; (len(records) > 0) != want || indexed != want {
+			t.Errorf("block %d: stored %v, indexed %v, want %v", i, len(records) > 0, indexed, want)
+		}
+	}
+}
+
+func TestDBObjDeleteDropsHashIndex(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hashes := make(map[uint64]*common.Hash)
+	for i := uint64(1); i <= 10; i++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(i))
+		hashes[i] = &hash
+		dbobj.setBlockRecords(db, i, &hash, testRecords("test", 1))
+	}
+	if _, err := dbobj.DeleteRange(context.Background(), db, 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.NewBatch()
+	if err := dbobj.DeleteBlockRange(batch, 6, 8, hashes); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 10; i++ {
+		if num, ok := dbobj.GetBlockNum(db, hashes[i]); ok != (i > 8) || (ok && num != i) {
+			t.Errorf("block %d: have %d %v", i, num, ok)
+		}
+	}
+	it := db.NewIteratorWithPrefix([]byte(hashIndexPre + dbobj.Pre))
+	defer it.Release()
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("have %d hash index entries, want 2", count)
+	}
+}
+
+func TestDBObjFindByLabel(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	hashes := make(map[uint64]*common.Hash)
+	for _, num := range []uint64{300, 2, 70000, 5} {
+		hash := common.BigToHash(new(big.Int).SetUint64(num))
+		hashes[num] = &hash
+		label := "mine"
+		if num == 5 {
+			label = "min"
+		}
+		if err := dbobj.SetBlockRecordsLabeled(db, num, &hash, label, testRecords("test", 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if nums, err := dbobj.FindByLabel(db, "mine"); err != nil || !reflect.DeepEqual(nums, []uint64{2, 300, 70000}) {
+		t.Fatalf("have %v %v, want [2 300 70000]", nums, err)
+	}
+	if nums, err := dbobj.FindByLabel(db, "min"); err != nil || !reflect.DeepEqual(nums, []uint64{5}) {
+		t.Fatalf("have %v %v, want [5]", nums, err)
+	}
+	if nums, err := dbobj.FindByLabel(db, "other"); err != nil || len(nums) != 0 {
+		t.Fatalf("have %v %v, want none", nums, err)
+	}
+	if max, _, _ := dbobj.MaxBlockNumber(db); max != 70000 {
+		t.Errorf("labels leaked into the records: max block %d", max)
+	}
+
+	batch := db.NewBatch()
+	if err := dbobj.DeleteBlockRecordsLabeled(batch, 300, hashes[300], "mine"); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if nums, err := dbobj.FindByLabel(db, "mine"); err != nil || !reflect.DeepEqual(nums, []uint64{2, 70000}) {
+		t.Fatalf("after delete: have %v %v, want [2 70000]", nums, err)
+	}
+	if records, _ := dbobj.GetBlockRecords(db, 300, hashes[300]); len(records) != 0 {
+		t.Error("records of block 300 not deleted")
+	}
+	if _, ok := dbobj.GetBlockNum(db, hashes[300]); ok {
+		t.Error("hash index of block 300 not deleted")
+	}
+
+	if _, err := dbobj.FindByLabel(db, ""); err == nil {
+		t.Error("expected error for an empty label")
+	}
+	if err := dbobj.SetBlockRecordsLabeled(db, 1, hashes[2], strings.Repeat("x", 256), nil); err == nil {
+		t.Error("expected error for an over-long label")
+	}
+}
+
+func TestDBObjCompressedRecords(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	legacy := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	compressed := DBObj{Pre: "BLOCK$CONS$INDEX$", Compressed: true}
+	plain, packed := common.HexToHash("0x01"), common.HexToHash("0x02")
+	plainRecords, packedRecords := testRecords("plain", 64), testRecords("packed", 64)
+	for i := range plainRecords[0].Pairs {
+		plainRecords[0].Pairs[i].Hash = plain[:]
+		packedRecords[0].Pairs[i].Hash = plain[:]
+	}
+	if _, err := legacy.setBlockRecords(db, 1, &plain, plainRecords); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compressed.setBlockRecords(db, 2, &packed, packedRecords); err != nil {
+		t.Fatal(err)
+	}
+
+	plainRaw, _ := db.Get(makeBlockName(legacy.Pre, 1, &plain))
+	packedRaw, _ := db.Get(makeBlockName(legacy.Pre, 2, &packed))
+	if plainRaw[0] < 0xc0 {
+		t.Fatalf("uncompressed blob starts with %#x, want an RLP list", plainRaw[0])
+	}
+	if packedRaw[0] != recordsSnappy || len(packedRaw) >= len(plainRaw) {
+		t.Fatalf("compressed blob: prefix %#x, %d bytes against %d uncompressed", packedRaw[0], len(packedRaw), len(plainRaw))
+	}
+
+	// either kind of entry is read by either DBObj
+	for _, obj := range []DBObj{legacy, compressed} {
+		if records, err := obj.GetBlockRecords(db, 1, &plain); err != nil || len(records) != 1 || records[0].Name != "plain" || len(records[0].Pairs) != 64 {
+			t.Errorf("compressed=%v: uncompressed entry: have %v %v", obj.Compressed, records, err)
+		}
+		if records, err := obj.GetBlockRecords(db, 2, &packed); err != nil || len(records) != 1 || records[0].Name != "packed" || len(records[0].Pairs) != 64 {
+			t.Errorf("compressed=%v: compressed entry: have %v %v", obj.Compressed, records, err)
+		}
+		if !obj.HasBlockRecords(db, 2, &packed) {
+			t.Errorf("compressed=%v: compressed entry has no records", obj.Compressed)
+		}
+		if n, err := obj.CountPairs(db, 2, &packed); err != nil || n != 64 {
+			t.Errorf("compressed=%v: have %d pairs %v, want 64", obj.Compressed, n, err)
+		}
+	}
+	if count, _, _, err := DecodeRecordsHeader(packedRaw); err != nil || count != 1 {
+		t.Errorf("header of compressed entry: have %d %v", count, err)
+	}
+	if issues, err := legacy.HealthCheck(context.Background(), db, 0, 10); err != nil || len(issues) != 0 {
+		t.Errorf("health check: have %v %v", issues, err)
+	}
+
+	// a corrupt compressed entry is reported, not decoded as garbage
+	broken := common.HexToHash("0x03")
+	db.Put(makeBlockName(legacy.Pre, 3, &broken), []byte{recordsSnappy, 0xff, 0xff})
+	if _, err := compressed.GetBlockRecords(db, 3, &broken); err == nil {
+		t.Error("expected error for a corrupt compressed entry")
+	}
+}
@@ -0,0 +1,291 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// forEachBlock calls fn for every stored block records entry whose number is
+// in [from,to]. Block numbers are stored with a variable width, so the entries
+// are visited in key order rather than in height order.
+func (self DBObj) forEachBlock(ctx context.Context, iter serodb.Iteratee, from, to uint64, fn func(num uint64, hash common.Hash, key []byte, value []byte) error) error {
+	it := iter.NewIteratorWithPrefix([]byte(self.Pre))
+	defer it.Release()
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		num, hash, ok := parseBlockName(self.Pre, it.Key())
+		if !ok || num < from || num > to {
+			continue
+		}
+		if err := fn(num, hash, it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+type jsonRecordPair struct {
+	Ref  hexutil.Bytes `json:"ref"`
+	Hash hexutil.Bytes `json:"hash"`
+}
+
+type jsonRecord struct {
+	Name  string           `json:"name"`
+	Pairs []jsonRecordPair `json:"pairs"`
+}
+
+type jsonBlockRecords struct {
+	Num     uint64       `json:"num"`
+	Hash    common.Hash  `json:"hash"`
+	Records []jsonRecord `json:"records"`
+}
+
+// ExportJSONL writes the block records in [from,to] to w as newline delimited
+// json, one object per block, and returns the number of blocks written.
+func (self DBObj) ExportJSONL(ctx context.Context, iter serodb.Iteratee, from, to uint64, w io.Writer) (int, error) {
+	count := 0
+	enc := json.NewEncoder(w)
+	err := self.forEachBlock(ctx, iter, from, to, func(num uint64, hash common.Hash, key []byte, value []byte) error {
+		value, err := decodeRecordsBlob(value)
+		if err != nil {
+			return fmt.Errorf("decode records of block %v(%v): %v", num, hash.Hex(), err)
+		}
+		var records []*Record
+		if err := rlp.DecodeBytes(value, &records); err != nil {
+			return err
+		}
+		block := jsonBlockRecords{Num: num, Hash: hash, Records: make([]jsonRecord, len(records))}
+		for i, r := range records {
+			block.Records[i].Name = r.Name
+			block.Records[i].Pairs = make([]jsonRecordPair, len(r.Pairs))
+			for j, pair := range r.Pairs {
+				block.Records[i].Pairs[j] = jsonRecordPair{pair.Ref, pair.Hash}
+			}
+		}
+		if err := enc.Encode(&block); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// RangeSize returns the total size of the stored block records in [from,to],
+// after compression for compressed entries.
+func (self DBObj) RangeSize(ctx context.Context, iter serodb.Iteratee, from, to uint64) (uint64, error) {
+	size := uint64(0)
+	err := self.forEachBlock(ctx, iter, from, to, func(num uint64, hash common.Hash, key []byte, value []byte) error {
+		size += uint64(len(value))
+		return nil
+	})
+	return size, err
+}
+
+// deleteRangeBatchSize bounds the number of blocks removed per batch write by
+// DeleteRange.
+const deleteRangeBatchSize = 1024
+
+// DeleteRange removes the block records in [from,to] along with their hash
+// index entries, committing every deleteRangeBatchSize blocks so pruning a
+// long range never builds one huge batch. It returns the number of blocks
+// deleted, which on cancellation or error counts only committed deletions.
+//
+// Block numbers are stored with a variable width, so no key bounds the range:
+// every block records entry under the prefix is read, however short the
+// range. The cost grows with the whole table, not with the range; prefer
+// DeleteBlockRange when the hashes of the blocks are known.
+func (self DBObj) DeleteRange(ctx context.Context, db serodb.Database, from, to uint64) (int, error) {
+	iter, ok := db.(serodb.Iteratee)
+	if !ok {
+		return 0, errors.New("delete range: database can not be iterated")
+	}
+	count, pending := 0, 0
+	batch := db.NewBatch()
+	flush := func() error {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		count += pending
+		pending = 0
+		return nil
+	}
+	err := self.forEachBlock(ctx, iter, from, to, func(num uint64, hash common.Hash, key []byte, value []byte) error {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+		if err := batch.Delete(makeHashIndexName(self.Pre, &hash)); err != nil {
+			return err
+		}
+		if pending++; pending >= deleteRangeBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if pending > 0 {
+		if e := flush(); err == nil {
+			err = e
+		}
+	}
+	return count, err
+}
+
+// DeleteBlockRange removes the block records of the blocks in [from,to] that
+// have a hash in hashes, along with their hash index entries, by deleting them
+// in the batch. Blocks without a hash, or without stored records, are skipped.
+// Unlike DeleteRange it needs no iteration, the hashes name the keys.
+func (self DBObj) DeleteBlockRange(batch serodb.Deleter, from, to uint64, hashes map[uint64]*common.Hash) error {
+	for num := from; num <= to; num++ {
+		if hash := hashes[num]; hash != nil {
+			if err := batch.Delete(makeBlockName(self.Pre, num, hash)); err != nil {
+				return err
+			}
+			if err := batch.Delete(makeHashIndexName(self.Pre, hash)); err != nil {
+				return err
+			}
+		}
+		if num == to {
+			break
+		}
+	}
+	return nil
+}
+
+// IterateObjects calls fn with the hash and decoded value of every object
+// stored under the prefix, in key order. Each value is decoded into a fresh
+// item from proto; an undecodable value stops the walk with an error. Only
+// keys of the prefix followed by a 32 byte hash are objects, so the keys of
+// another table whose prefix starts with this one are skipped. The prefix must
+// not be shared with block records, whose keys can have that length too.
+func (self DBObj) IterateObjects(iter serodb.Iteratee, proto func() CItem, fn func(hash []byte, item CItem) error) error {
+	var err error
+	e := self.IterateRawObjects(iter, func(hash []byte, raw []byte) bool {
+		item := proto()
+		if err = rlp.DecodeBytes(raw, item); err != nil {
+			err = fmt.Errorf("decode object %x: %v", hash, err)
+			return false
+		}
+		err = fn(hash, item)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return e
+}
+
+// IterateRawObjects calls fn with the hash and the undecoded value of every
+// object stored under the prefix, in key order, until it returns false. The
+// hash is a copy, the value is only valid during the call. As for
+// IterateObjects, only keys of the prefix followed by a 32 byte hash are
+// visited, and the prefix must not be shared with block records.
+func (self DBObj) IterateRawObjects(iter serodb.Iteratee, fn func(hash []byte, raw []byte) bool) error {
+	it := iter.NewIteratorWithPrefix([]byte(self.Pre))
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != len(self.Pre)+common.HashLength {
+			continue
+		}
+		if !fn(common.CopyBytes(it.Key()[len(self.Pre):]), it.Value()) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// maxHealthIssues bounds the number of issues HealthCheck collects.
+const maxHealthIssues = 1024
+
+// HealthIssue is a block records entry that failed to decode.
+type HealthIssue struct {
+	Num  uint64
+	Hash common.Hash
+	Err  error
+}
+
+// HealthCheck decodes every block records entry in [from,to] and reports the
+// ones that fail instead of panicking on them. Only the issues are kept in
+// memory; the scan stops with an error once maxHealthIssues were found.
+func (self DBObj) HealthCheck(ctx context.Context, iter serodb.Iteratee, from, to uint64) ([]HealthIssue, error) {
+	var issues []HealthIssue
+	err := self.forEachBlock(ctx, iter, from, to, func(num uint64, hash common.Hash, key []byte, value []byte) error {
+		value, err := decodeRecordsBlob(value)
+		if err == nil {
+			var records []*Record
+			err = rlp.DecodeBytes(value, &records)
+		}
+		if err != nil {
+			issues = append(issues, HealthIssue{num, hash, err})
+			if len(issues) >= maxHealthIssues {
+				return fmt.Errorf("health check: stopped after %d issues", len(issues))
+			}
+		}
+		return nil
+	})
+	return issues, err
+}
+
+// MaxBlockNumber returns the highest block number with stored records, ok is
+// false when there are none. Block numbers are stored with a variable width,
+// so the last key is not necessarily the highest block: every block records
+// entry under the prefix is read, which costs a full scan of the table on
+// each call. Callers needing it often should keep the result.
+func (self DBObj) MaxBlockNumber(iter serodb.Iteratee) (max uint64, ok bool, err error) {
+	err = self.forEachBlock(context.Background(), iter, 0, math.MaxUint64, func(num uint64, hash common.Hash, key []byte, value []byte) error {
+		if !ok || num > max {
+			max, ok = num, true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return max, ok, nil
+}
+
+// CompactKeyRange compacts the block records of [from,to] in the database,
+// typically after a DeleteRange, so the space of the removed entries is
+// reclaimed. Block numbers are stored with a variable width, so the range is
+// compacted as one key range per width, which may take in neighbouring keys
+// of other widths too. It is a no-op for backends that do not implement
+// serodb.Compacter, such as the memory database.
+func (self DBObj) CompactKeyRange(db serodb.Database, from, to uint64) error {
+	compacter, ok := db.(serodb.Compacter)
+	if !ok || from > to {
+		return nil
+	}
+	for lo := from; ; {
+		// the highest number stored with the same width as lo
+		hi := uint64(math.MaxUint64)
+		if width := len(big.NewInt(0).SetUint64(lo).Bytes()); width < 8 {
+			hi = uint64(1)<<(8*uint(width)) - 1
+		}
+		if hi > to {
+			hi = to
+		}
+		start := append([]byte(self.Pre), big.NewInt(0).SetUint64(lo).Bytes()...)
+		limit := append([]byte(self.Pre), big.NewInt(0).SetUint64(hi).Bytes()...)
+		limit = append(limit, bytes.Repeat([]byte{0xff}, common.HashLength+1)...)
+		if err := compacter.Compact(start, limit); err != nil {
+			return err
+		}
+		if hi == to {
+			return nil
+		}
+		lo = hi + 1
+	}
+}
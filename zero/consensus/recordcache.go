@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+type recordCacheKey struct {
+	num  uint64
+	hash common.Hash
+}
+
+// RecordCache keeps the decoded records of the most recently read blocks in
+// front of a DBObj, so reading the same block again skips the database and
+// the RLP decoding. It is safe for concurrent use. The cached records are
+// shared between readers and must not be modified.
+type RecordCache struct {
+	obj   DBObj
+	cache *lru.Cache
+
+	lock sync.Mutex
+	gen  uint64 // bumped by Invalidate, see GetBlockRecords
+}
+
+// NewRecordCache creates a cache holding the records of up to size blocks.
+func NewRecordCache(obj DBObj, size int) (*RecordCache, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordCache{obj: obj, cache: cache}, nil
+}
+
+// GetBlockRecords works like DBObj.GetBlockRecords, serving the records from
+// the cache when they are there. Blocks without records are not cached.
+func (self *RecordCache) GetBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash) ([]*Record, error) {
+	k := recordCacheKey{num, *hash}
+	if records, ok := self.cache.Get(k); ok {
+		return records.([]*Record), nil
+	}
+	self.lock.Lock()
+	gen := self.gen
+	self.lock.Unlock()
+
+	records, err := self.obj.GetBlockRecords(getter, num, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		// records read before an invalidation may predate the write that
+		// caused it, so they are returned but not cached
+		self.lock.Lock()
+		if gen == self.gen {
+			self.cache.Add(k, records)
+		}
+		self.lock.Unlock()
+	}
+	return records, nil
+}
+
+// SetBlockRecords stores the records of the block in the batch. Until the
+// batch is written readers keep seeing, and may cache, the old records, so
+// callers must call Invalidate for the block once the batch is written.
+func (self *RecordCache) SetBlockRecords(batch serodb.Putter, num uint64, hash *common.Hash, records []*Record) error {
+	_, err := self.obj.setBlockRecords(batch, num, hash, records)
+	return err
+}
+
+// Invalidate drops the records cached for the block, if any, including those
+// of reads still in flight.
+func (self *RecordCache) Invalidate(num uint64, hash *common.Hash) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.gen++
+	self.cache.Remove(recordCacheKey{num, *hash})
+}
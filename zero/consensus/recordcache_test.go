@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+type countingGetter struct {
+	serodb.Getter
+	gets int32
+}
+
+func (g *countingGetter) Get(key []byte) ([]byte, error) {
+	atomic.AddInt32(&g.gets, 1)
+	return g.Getter.Get(key)
+}
+
+func TestRecordCache(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	getter := &countingGetter{Getter: db}
+	dbobj := DBObj{Pre: "BLOCK$CONS$INDEX$"}
+	cache, err := NewRecordCache(dbobj, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make([]common.Hash, 3)
+	for i := range hashes {
+		hashes[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+		dbobj.setBlockRecords(db, uint64(i), &hashes[i], testRecords("test", i+1))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if records, err := cache.GetBlockRecords(getter, 0, &hashes[0]); err != nil || len(records) != 1 {
+				t.Errorf("have %v %v", records, err)
+			}
+		}()
+	}
+	wg.Wait()
+	gets := atomic.LoadInt32(&getter.gets)
+	if records, _ := cache.GetBlockRecords(getter, 0, &hashes[0]); len(records) != 1 || getter.gets != gets {
+		t.Fatalf("second read: have %d records and %d gets, want a cached read", len(records), getter.gets-gets)
+	}
+
+	// a rewrite shows once its batch is written and the block invalidated,
+	// even if the old records were read in between
+	batch := db.NewBatch()
+	if err := cache.SetBlockRecords(batch, 0, &hashes[0], testRecords("new", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := cache.GetBlockRecords(getter, 0, &hashes[0]); len(records) != 1 || records[0].Name != "test" {
+		t.Fatalf("before commit: have %v", records)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	cache.Invalidate(0, &hashes[0])
+	if records, _ := cache.GetBlockRecords(getter, 0, &hashes[0]); len(records) != 1 || records[0].Name != "new" {
+		t.Fatalf("after rewrite: have %v", records)
+	}
+
+	// reading two more blocks evicts the least recently used one
+	cache.GetBlockRecords(getter, 1, &hashes[1])
+	cache.GetBlockRecords(getter, 2, &hashes[2])
+	gets = getter.gets
+	cache.GetBlockRecords(getter, 0, &hashes[0])
+	if getter.gets != gets+1 {
+		t.Error("evicted block was served from the cache")
+	}
+
+	// a failed write leaves the cached records alone
+	gets = getter.gets
+	if err := cache.SetBlockRecords(failingDB{db}, 0, &hashes[0], testRecords("failed", 1)); err != errFailingDB {
+		t.Fatalf("have %v, want %v", err, errFailingDB)
+	}
+	if records, _ := cache.GetBlockRecords(getter, 0, &hashes[0]); len(records) != 1 || records[0].Name != "new" || getter.gets != gets {
+		t.Fatalf("after failed write: have %v", records)
+	}
+
+	if _, err := NewRecordCache(dbobj, 0); err == nil {
+		t.Error("expected error for a zero size")
+	}
+}
@@ -278,8 +278,8 @@ type StakeState struct {
 }
 
 var (
-	ShareDB             = consensus.DBObj{"STAKE$SHARE$"}
-	StakePoolDB         = consensus.DBObj{"STAKE$POOL$"}
+	ShareDB             = consensus.DBObj{Pre: "STAKE$SHARE$"}
+	StakePoolDB         = consensus.DBObj{Pre: "STAKE$POOL$"}
 	missedNumKey        = []byte("missednum")
 	blockVotesPrefix    = []byte("STAKE$BLOCKVOTES$")
 	blockShareNumPrefix = []byte("STAKE$SHARE$NUM$")
@@ -490,21 +490,34 @@ func (self *StakeState) GetShare(key common.Hash) *Share {
 	return item.(*Share)
 }
 
-func GetStakePoolByBlockNumber(getter serodb.Getter, id common.Hash, blockHash common.Hash, blockNumber uint64) *StakePool {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+// blockRecords returns the stake records of the block, none for a block
+// without records.
+func blockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) ([]*consensus.Record, error) {
+	records, err := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("get stake block records: %v", err)
+	}
+	return records, nil
+}
+
+func GetStakePoolByBlockNumber(getter serodb.Getter, id common.Hash, blockHash common.Hash, blockNumber uint64) (pool *StakePool, err error) {
+	records, err := blockRecords(getter, blockHash, blockNumber)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "pool" {
 			for _, each := range record.Pairs {
 				if bytes.Equal(id[:], each.Ref) {
 					ret := StakePoolDB.GetObject(getter, each.Hash, &StakePool{})
 					if ret != nil {
-						return ret.(*StakePool)
+						return ret.(*StakePool), nil
 					}
 				}
 			}
 		}
 	}
-	return nil
+	return
 }
 
 func (self *StakeState) GetStakePool(poolId common.Hash) *StakePool {
@@ -515,8 +528,11 @@ func (self *StakeState) GetStakePool(poolId common.Hash) *StakePool {
 	return item.(*StakePool)
 }
 
-func GetBlockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, pools []*StakePool) {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+func GetBlockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, pools []*StakePool, err error) {
+	records, err := blockRecords(getter, blockHash, blockNumber)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "share" {
 			for _, each := range record.Pairs {
@@ -535,7 +551,10 @@ func GetBlockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber ui
 }
 
 func (self *StakeState) getBlockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, pools []*StakePool, err error) {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+	records, err := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "share" {
 			for _, each := range record.Pairs {
@@ -568,25 +587,31 @@ func GetShare(getter serodb.Getter, hash common.Hash) *Share {
 	return ret.(*Share)
 }
 
-func GetShareByBlockNumber(getter serodb.Getter, id common.Hash, blockHash common.Hash, blockNumber uint64) *Share {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+func GetShareByBlockNumber(getter serodb.Getter, id common.Hash, blockHash common.Hash, blockNumber uint64) (share *Share, err error) {
+	records, err := blockRecords(getter, blockHash, blockNumber)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "share" {
 			for _, each := range record.Pairs {
 				if bytes.Equal(id[:], each.Ref) {
 					ret := ShareDB.GetObject(getter, each.Hash, &Share{})
 					if ret != nil {
-						return ret.(*Share)
+						return ret.(*Share), nil
 					}
 				}
 			}
 		}
 	}
-	return nil
+	return
 }
 
-func GetSharesByBlock(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share) {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+func GetSharesByBlock(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, err error) {
+	records, err := blockRecords(getter, blockHash, blockNumber)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "share" {
 			for _, each := range record.Pairs {
@@ -603,7 +628,10 @@ func GetSharesByBlock(getter serodb.Getter, blockHash common.Hash, blockNumber u
 }
 
 func (self *StakeState) getShares(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, err error) {
-	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+	records, err := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
+	if err != nil {
+		return
+	}
 	for _, record := range records {
 		if record.Name == "share" {
 			for _, each := range record.Pairs {
@@ -859,7 +887,7 @@ func (self *StakeState) processRemedyRewards(bc blockChain, header *types.Header
 			}
 			asset := assets.Asset{
 				&assets.Token{
-					utils.CurrencyToUint256("SERO"),
+					utils.SeroCurrency,
 					utils.U256(*reward),
 				},
 				nil,
@@ -1202,7 +1230,10 @@ func (self *StakeState) processMissVoted(header *types.Header, bc blockChain) (e
 
 func (self *StakeState) processNowShares(header *types.Header, bc blockChain) (err error) {
 	perHeader := bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
-	shares := GetSharesByBlock(bc.GetDB(), perHeader.Hash(), perHeader.Number.Uint64())
+	shares, err := GetSharesByBlock(bc.GetDB(), perHeader.Hash(), perHeader.Number.Uint64())
+	if err != nil {
+		return
+	}
 	// shares := self.getShares(bc.GetDB(), perHeader.Hash(), perHeader.Number.Uint64(), shareCacheMap)
 	if len(shares) > 0 {
 		for _, share := range shares {
@@ -9,12 +9,12 @@ import (
 )
 
 var sero_token = Token{
-	utils.CurrencyToUint256("SERO"),
+	utils.CurrencyToUint256Unchecked("SERO"),
 	utils.NewU256(100),
 }
 
 var tk_ticket = Ticket{
-	utils.CurrencyToUint256("TK"),
+	utils.CurrencyToUint256Unchecked("TK"),
 	c_type.RandUint256(),
 }
 
@@ -36,7 +36,7 @@ func (self *BuyShareCmd) ToHash() (ret c_type.Uint256) {
 
 func (self *BuyShareCmd) Asset() (ret assets.Asset) {
 	ret.Tkn = &assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		self.Value,
 	}
 	return
@@ -59,7 +59,7 @@ func (self *RegistPoolCmd) ToHash() (ret c_type.Uint256) {
 
 func (self *RegistPoolCmd) Asset() (ret assets.Asset) {
 	ret.Tkn = &assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		self.Value,
 	}
 	return
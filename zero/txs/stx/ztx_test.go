@@ -31,7 +31,7 @@ func TestRLP(t *testing.T) {
 
 	tx := T{}
 	tx.Fee.Value = utils.NewU256(2)
-	tx.Fee.Currency = utils.CurrencyToUint256("SERO")
+	tx.Fee.Currency = utils.CurrencyToUint256Unchecked("SERO")
 	tx.Desc_Cmd.RegistPool = &RegistPoolCmd{}
 	tx.Desc_Cmd.RegistPool.Value = utils.NewU256(3)
 	tx.Desc_Cmd.RegistPool.FeeRate = 10
@@ -55,7 +55,7 @@ func TestClose(t *testing.T) {
 
 	tx := T{}
 	tx.Fee.Value = utils.NewU256(2)
-	tx.Fee.Currency = utils.CurrencyToUint256("SERO")
+	tx.Fee.Currency = utils.CurrencyToUint256Unchecked("SERO")
 	tx.Desc_Cmd.ClosePool = &ClosePoolCmd{}
 
 	e := rlp.Encode(w, &tx)
@@ -63,29 +63,43 @@ type T struct {
 }
 
 func (self *T) TokenCost() (ret map[c_type.Uint256]utils.U256) {
-	ret = make(map[c_type.Uint256]utils.U256)
-	ret[self.Fee.Currency] = self.Fee.Value
+	cost := utils.TokenCostMap{}
+	cost.Add(self.Fee.Currency, self.Fee.Value)
 	if len(self.Outs) > 0 {
 		for _, out := range self.Outs {
 			if out.Asset.Tkn != nil {
-				if cost, ok := ret[out.Asset.Tkn.Currency]; ok {
-					cost.AddU(&out.Asset.Tkn.Value)
-					ret[out.Asset.Tkn.Currency] = cost
-				} else {
-					ret[out.Asset.Tkn.Currency] = out.Asset.Tkn.Value
-				}
+				cost.Add(out.Asset.Tkn.Currency, out.Asset.Tkn.Value)
 			}
 		}
 	}
 	if self.PkgCreate != nil {
 		asset := self.PkgCreate.Pkg.Asset
 		if asset.Tkn != nil {
-			if cost, ok := ret[asset.Tkn.Currency]; ok {
-				cost.AddU(&asset.Tkn.Value)
-				ret[asset.Tkn.Currency] = cost
-			} else {
-				ret[asset.Tkn.Currency] = asset.Tkn.Value
-			}
+			cost.Add(asset.Tkn.Currency, asset.Tkn.Value)
+		}
+	}
+	return cost
+}
+
+// DiffTokenCost reconciles two token cost maps, as returned by TokenCost. Each
+// reported entry holds the expected and the actual amount, in that order.
+// Currencies only in expected are missing (with a zero actual amount), those
+// only in actual are extra (with a zero expected amount) and those in both
+// with different amounts are mismatched.
+func DiffTokenCost(expected, actual map[c_type.Uint256]utils.U256) (missing, extra, mismatched map[c_type.Uint256][2]utils.U256) {
+	missing = make(map[c_type.Uint256][2]utils.U256)
+	extra = make(map[c_type.Uint256][2]utils.U256)
+	mismatched = make(map[c_type.Uint256][2]utils.U256)
+	for currency, want := range expected {
+		if have, ok := actual[currency]; !ok {
+			missing[currency] = [2]utils.U256{want, utils.NewU256(0)}
+		} else if want.Cmp(&have) != 0 {
+			mismatched[currency] = [2]utils.U256{want, have}
+		}
+	}
+	for currency, have := range actual {
+		if _, ok := expected[currency]; !ok {
+			extra[currency] = [2]utils.U256{utils.NewU256(0), have}
 		}
 	}
 	return
@@ -9,9 +9,9 @@ import (
 )
 
 func TestT_TokenCost(t *testing.T) {
-	seroCy := utils.CurrencyToUint256("SERO")
+	seroCy := utils.CurrencyToUint256Unchecked("SERO")
 	fmt.Printf("%t\n", seroCy)
-	cy := utils.CurrencyToUint256("d")
+	cy := utils.CurrencyToUint256Unchecked("d")
 	ret := make(map[c_type.Uint256]utils.U256)
 	ret[seroCy] = utils.NewU256(24)
 	if cost, ok := ret[seroCy]; ok {
@@ -26,3 +26,23 @@ func TestT_TokenCost(t *testing.T) {
 	fmt.Printf("%t", ret)
 
 }
+
+func TestDiffTokenCost(t *testing.T) {
+	sero, a, b, c := utils.CurrencyToUint256Unchecked("SERO"), utils.CurrencyToUint256Unchecked("A"), utils.CurrencyToUint256Unchecked("B"), utils.CurrencyToUint256Unchecked("C")
+	expected := map[c_type.Uint256]utils.U256{sero: utils.NewU256(10), a: utils.NewU256(5), b: utils.NewU256(7)}
+	actual := map[c_type.Uint256]utils.U256{sero: utils.NewU256(10), b: utils.NewU256(8), c: utils.NewU256(1)}
+
+	sameCost := func(pair [2]utils.U256, want, have uint64) bool {
+		return pair[0].ToIntRef().Uint64() == want && pair[1].ToIntRef().Uint64() == have
+	}
+	missing, extra, mismatched := DiffTokenCost(expected, actual)
+	if len(missing) != 1 || !sameCost(missing[a], 5, 0) {
+		t.Errorf("missing: %v", missing)
+	}
+	if len(extra) != 1 || !sameCost(extra[c], 0, 1) {
+		t.Errorf("extra: %v", extra)
+	}
+	if len(mismatched) != 1 || !sameCost(mismatched[b], 7, 8) {
+		t.Errorf("mismatched: %v", mismatched)
+	}
+}
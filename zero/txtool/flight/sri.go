@@ -162,7 +162,7 @@ func GenTxParam(param *PreTxParam, tk c_type.Tk) (p txtool.GTxParam, e error) {
 	p.Gas = param.Gas
 	p.GasPrice = big.NewInt(0).SetUint64(param.GasPrice)
 	p.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*new(big.Int).Mul(new(big.Int).SetUint64(param.Gas), new(big.Int).SetUint64(param.GasPrice))),
 	}
 	p.From.PKr = param.From
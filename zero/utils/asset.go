@@ -0,0 +1,75 @@
+// copyright 2018 The sero.cash Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+)
+
+// Asset is an amount of a token, the (currency, value) pair contracts pass
+// around as an abi tuple. The abi package packs and unpacks it directly for a
+// tuple whose currency is a bytes32 or uint256 and whose value is a uint256.
+type Asset struct {
+	Currency c_type.Uint256
+	Value    U256
+}
+
+// NewAsset returns an amount of the named currency, failing for an invalid
+// currency name.
+func NewAsset(currency string, value U256) (Asset, error) {
+	c, err := CurrencyToUint256(currency)
+	if err != nil {
+		return Asset{}, err
+	}
+	return Asset{c, value}, nil
+}
+
+// TokenCostMap sums token amounts per currency, as done to work out the cost
+// of a transaction. Amounts are copied in, so the map never shares a value
+// with its callers.
+type TokenCostMap map[c_type.Uint256]U256
+
+// Add adds amount to the total of the currency.
+func (self TokenCostMap) Add(cy c_type.Uint256, amount U256) {
+	if cost, ok := self[cy]; ok {
+		cost.AddU(&amount)
+		self[cy] = cost
+	} else {
+		self[cy] = amount.Clone()
+	}
+}
+
+// Sub takes amount off the total of the currency, leaving it unchanged and
+// returning an error if the total would go below zero.
+func (self TokenCostMap) Sub(cy c_type.Uint256, amount U256) error {
+	cost := self.Total(cy)
+	if err := cost.SubU(&amount); err != nil {
+		return fmt.Errorf("token cost of %v: %v", Uint256ToCurrency(&cy), err)
+	}
+	self[cy] = cost
+	return nil
+}
+
+// Total returns the total of the currency, zero if there is none.
+func (self TokenCostMap) Total(cy c_type.Uint256) U256 {
+	if cost, ok := self[cy]; ok {
+		return cost.Clone()
+	}
+	return NewU256(0)
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"io"
 	"math/big"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-czero-import/seroparam"
 
+	"github.com/sero-cash/go-sero/common/math"
 	"github.com/sero-cash/go-sero/rlp"
 )
 
@@ -43,6 +45,14 @@ func (self U256) DeepCopy() interface{} {
 	return dc
 }
 
+// Clone returns a deep copy of the value. U256 is a big.Int underneath, so a
+// plain assignment shares the word slice with the original and an in-place
+// big.Int operation on one copy can show up in the other.
+func (self U256) Clone() U256 {
+	bi := big.Int(self)
+	return U256(*new(big.Int).Set(&bi))
+}
+
 func (x *U256) GobEncode() ([]byte, error) {
 	b := big.Int(*x)
 	return b.GobEncode()
@@ -85,6 +95,33 @@ func (b *U256) DecodeRLP(s *rlp.Stream) error {
 	}
 }
 
+// U256Fixed is a U256 that RLP encodes as a 32 byte big endian string, so the
+// encoding of an amount has the same length whatever its magnitude. Prefer it
+// over U256 in new consensus structures that are hashed, where a value
+// dependent length would make equal layouts encode differently. U256 keeps
+// its variable length gob based encoding for the data already stored with it.
+type U256Fixed U256
+
+func (b U256Fixed) EncodeRLP(w io.Writer) error {
+	i := big.Int(b)
+	if i.Sign() < 0 || i.BitLen() > 256 {
+		return errors.Errorf("u256 fixed encode error: %v out of range", &i)
+	}
+	return rlp.Encode(w, math.PaddedBigBytes(&i, 32))
+}
+
+func (b *U256Fixed) DecodeRLP(s *rlp.Stream) error {
+	bytes, e := s.Bytes()
+	if e != nil {
+		return e
+	}
+	if len(bytes) != 32 {
+		return errors.Errorf("u256 fixed decode error: have %v bytes, want 32", len(bytes))
+	}
+	*b = U256Fixed(*new(big.Int).SetBytes(bytes))
+	return nil
+}
+
 func isString(input []byte) bool {
 	return len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"'
 }
@@ -121,6 +158,9 @@ func (b *U256) ToBEBytes() (ret []byte) {
 //	return i.MarshalText()
 //}
 
+// UnmarshalJSON accepts a json number or string holding a decimal or 0x
+// prefixed hex integer. Values that are negative or do not fit in 256 bits
+// are rejected.
 func (b *U256) UnmarshalJSON(input []byte) error {
 	if isString(input) {
 		input = input[1 : len(input)-1]
@@ -128,22 +168,60 @@ func (b *U256) UnmarshalJSON(input []byte) error {
 	i := big.Int{}
 	if e := i.UnmarshalJSON(input); e != nil {
 		return e
+	} else if e := checkU256Range(&i); e != nil {
+		return e
 	} else {
 		*b = U256(i)
 		return nil
 	}
 }
 
+// UnmarshalText accepts a decimal or 0x prefixed hex integer, rejecting
+// values that are negative or do not fit in 256 bits.
 func (b *U256) UnmarshalText(input []byte) error {
 	i := big.Int{}
 	if e := i.UnmarshalText(input); e != nil {
 		return e
+	} else if e := checkU256Range(&i); e != nil {
+		return e
 	} else {
 		*b = U256(i)
 		return nil
 	}
 }
 
+func checkU256Range(i *big.Int) error {
+	if i.Sign() < 0 {
+		return errors.Errorf("u256: negative value %v", i)
+	}
+	if i.BitLen() > 256 {
+		return errors.Errorf("u256: value %v exceeds 256 bits", i)
+	}
+	return nil
+}
+
+// Balances maps currency tickers to amounts. Amounts are always encoded as
+// decimal strings in json, regardless of the exchange value mode of U256.
+type Balances map[string]U256
+
+func (self Balances) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(self))
+	for k, v := range self {
+		i := big.Int(v)
+		m[k] = i.String()
+	}
+	return json.Marshal(m)
+}
+
+func (self *Balances) UnmarshalJSON(input []byte) error {
+	m := make(map[string]U256)
+	if e := json.Unmarshal(input, &m); e != nil {
+		return e
+	}
+	*self = Balances(m)
+	return nil
+}
+
 func NewU256_ByKey(k *c_type.Uint256) (ret U256) {
 	bytes := *k.NewRef()
 	for i := 0; i < len(bytes)/2; i++ {
@@ -199,19 +277,97 @@ func (self *U256) AddU(a *U256) {
 	return
 }
 
+// SumU256 adds up the values, returning an error instead of the total if it
+// does not fit in 256 bits.
+func SumU256(vals []U256) (U256, error) {
+	sum := new(big.Int)
+	for i := range vals {
+		sum.Add(sum, (*big.Int)(&vals[i]))
+		if sum.BitLen() > 256 {
+			return U256_0.Clone(), errors.Errorf("u256 sum error: overflow at value %v", i)
+		}
+	}
+	return U256(*sum), nil
+}
+
 func (self *U256) Cmp(a *U256) int {
 	l := big.Int(*self)
 	r := big.Int(*a)
 	return l.Cmp(&r)
 }
 
-func (self *U256) SubU(a *U256) {
+// IsZero reports whether the value is zero.
+func (self U256) IsZero() bool {
+	return self.Cmp(&U256{}) == 0
+}
+
+// Lt reports whether the value is less than v.
+func (self U256) Lt(v U256) bool {
+	return self.Cmp(&v) < 0
+}
+
+// Gt reports whether the value is greater than v.
+func (self U256) Gt(v U256) bool {
+	return self.Cmp(&v) > 0
+}
+
+// MinU256 returns a copy of the smaller of a and b.
+func MinU256(a, b U256) U256 {
+	if a.Cmp(&b) <= 0 {
+		return a.Clone()
+	}
+	return b.Clone()
+}
+
+// MaxU256 returns a copy of the larger of a and b.
+func MaxU256(a, b U256) U256 {
+	if a.Cmp(&b) >= 0 {
+		return a.Clone()
+	}
+	return b.Clone()
+}
+
+// Clamp returns a copy of the value limited to [lo,hi]. If lo is above hi,
+// lo is returned.
+func (self U256) Clamp(lo, hi U256) U256 {
+	return MaxU256(lo, MinU256(self, hi))
+}
+
+// SubU subtracts a from the value. A result below zero is reported as an
+// error and leaves the value unchanged, balances never wrap around.
+func (self *U256) SubU(a *U256) error {
 	if self.Cmp(a) < 0 {
-		panic(errors.New("u256 sub error: result is negative"))
+		return errors.New("u256 sub error: result is negative")
 	}
 	l := big.Int(*self.ToRef())
 	r := big.Int(*a)
 	l.Sub(&l, &r)
 	*self = U256(l)
-	return
+	return nil
+}
+
+// MulU multiplies the value by a. A product beyond 256 bits is reported as an
+// error and leaves the value unchanged.
+func (self *U256) MulU(a *U256) error {
+	l := big.Int(*self.ToRef())
+	r := big.Int(*a)
+	l.Mul(&l, &r)
+	if l.BitLen() > 256 {
+		return errors.New("u256 mul error: result exceeds 256 bits")
+	}
+	*self = U256(l)
+	return nil
+}
+
+// DivU divides the value by a, truncating the quotient. Dividing by zero is
+// reported as an error and leaves the value unchanged.
+func (self *U256) DivU(a *U256) error {
+	r := big.Int(*a)
+	if r.Sign() == 0 {
+		return errors.New("u256 div error: division by zero")
+	}
+	l := big.Int(*self.ToRef())
+	l.Quo(&l, &r)
+	*self = U256(l)
+	return nil
 }
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/rlp"
 )
 
 func TestU256_MarshalText(t *testing.T) {
@@ -55,3 +57,211 @@ func TestU256_ToBEBytes(t *testing.T) {
 	r := a.ToBEBytes()
 	fmt.Println(hexutil.Encode(r))
 }
+
+func TestU256_Clone(t *testing.T) {
+	a := NewU256(1000000)
+	b := a.Clone()
+	b.ToInt().Add(b.ToInt(), big.NewInt(1))
+	if a.ToInt().Cmp(big.NewInt(1000000)) != 0 {
+		t.Fatalf("original changed to %v", a.ToInt())
+	}
+	if b.ToInt().Cmp(big.NewInt(1000001)) != 0 {
+		t.Fatalf("clone is %v", b.ToInt())
+	}
+}
+
+func TestBalances_JSON(t *testing.T) {
+	b := Balances{"SERO": NewU256(1000000000000000000), "GAS": U256_0}
+	m, e := json.Marshal(b)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(m) != `{"GAS":"0","SERO":"1000000000000000000"}` {
+		t.Fatalf("unexpected json %s", m)
+	}
+	var d Balances
+	if e := json.Unmarshal(m, &d); e != nil {
+		t.Fatal(e)
+	}
+	sero := d["SERO"]
+	if len(d) != 2 || sero.ToInt().Cmp(big.NewInt(1000000000000000000)) != 0 {
+		t.Fatalf("unexpected balances %v", d)
+	}
+}
+
+func TestSumU256(t *testing.T) {
+	sum, err := SumU256([]U256{NewU256(1), NewU256(2), NewU256(3)})
+	if err != nil || sum.ToIntRef().Uint64() != 6 {
+		t.Fatalf("have %v %v, want 6", sum.ToIntRef(), err)
+	}
+	if sum, err := SumU256(nil); err != nil || sum.ToIntRef().Sign() != 0 {
+		t.Fatalf("empty: have %v %v, want 0", sum.ToIntRef(), err)
+	}
+	max := U256(*new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)))
+	if sum, err := SumU256([]U256{max, NewU256(0)}); err != nil || sum.ToIntRef().BitLen() != 256 {
+		t.Fatalf("max: have %v %v", sum.ToIntRef(), err)
+	}
+	if _, err := SumU256([]U256{max, NewU256(1)}); err == nil {
+		t.Fatal("expected overflow error")
+	}
+}
+
+func TestU256Fixed_RLP(t *testing.T) {
+	for _, v := range []uint64{0, 1, 1 << 40} {
+		b, err := rlp.EncodeToBytes(U256Fixed(NewU256(v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != 33 {
+			t.Errorf("%v: have %d bytes, want 33", v, len(b))
+		}
+		var dec U256Fixed
+		if err := rlp.DecodeBytes(b, &dec); err != nil {
+			t.Fatal(err)
+		}
+		if u := U256(dec); u.ToIntRef().Uint64() != v {
+			t.Errorf("have %v, want %v", u.ToIntRef(), v)
+		}
+	}
+	short, _ := rlp.EncodeToBytes([]byte{1})
+	var dec U256Fixed
+	if err := rlp.DecodeBytes(short, &dec); err == nil {
+		t.Error("expected error for short value")
+	}
+	if _, err := rlp.EncodeToBytes(U256Fixed(*new(big.Int).Lsh(big.NewInt(1), 256))); err == nil {
+		t.Error("expected error for value over 256 bits")
+	}
+}
+
+func TestU256_SubU(t *testing.T) {
+	v := NewU256(10)
+	sub := NewU256(10)
+	if err := v.SubU(&sub); err != nil || v.ToIntRef().Sign() != 0 {
+		t.Fatalf("have %v %v, want 0", v.ToIntRef(), err)
+	}
+	one := NewU256(1)
+	if err := v.SubU(&one); err == nil {
+		t.Fatal("expected underflow error")
+	}
+	if v.ToIntRef().Sign() != 0 {
+		t.Errorf("value changed by failed subtraction: %v", v.ToIntRef())
+	}
+}
+
+func TestU256_MinMaxClamp(t *testing.T) {
+	val := func(u U256) uint64 { return u.ToIntRef().Uint64() }
+	a, b := NewU256(3), NewU256(7)
+	if val(MinU256(a, b)) != 3 || val(MinU256(b, a)) != 3 || val(MaxU256(a, b)) != 7 || val(MaxU256(b, a)) != 7 {
+		t.Fatal("min/max mismatch")
+	}
+	if val(MinU256(a, a)) != 3 || val(MaxU256(b, b)) != 7 {
+		t.Fatal("min/max of equal values mismatch")
+	}
+	for v, want := range map[uint64]uint64{0: 3, 3: 3, 5: 5, 7: 7, 100: 7} {
+		if have := val(NewU256(v).Clamp(a, b)); have != want {
+			t.Errorf("clamp %d: have %d, want %d", v, have, want)
+		}
+	}
+	min := MinU256(a, b)
+	min.AddU(&b)
+	if val(a) != 3 {
+		t.Error("result shares its value with the argument")
+	}
+}
+
+func TestU256_Compare(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	top, below := U256(*max), U256(*new(big.Int).Sub(max, big.NewInt(1)))
+	zero, one := U256{}, NewU256(1)
+	tests := []struct {
+		a, b   U256
+		cmp    int
+		lt, gt bool
+	}{
+		{zero, zero, 0, false, false},
+		{zero, one, -1, true, false},
+		{one, zero, 1, false, true},
+		{top, top, 0, false, false},
+		{below, top, -1, true, false},
+		{top, below, 1, false, true},
+		{zero, top, -1, true, false},
+	}
+	for i, test := range tests {
+		if cmp := test.a.Cmp(&test.b); cmp != test.cmp {
+			t.Errorf("%d: cmp have %d, want %d", i, cmp, test.cmp)
+		}
+		if lt, gt := test.a.Lt(test.b), test.a.Gt(test.b); lt != test.lt || gt != test.gt {
+			t.Errorf("%d: have lt %v gt %v, want %v %v", i, lt, gt, test.lt, test.gt)
+		}
+	}
+	if !zero.IsZero() || !NewU256(0).IsZero() || one.IsZero() || top.IsZero() {
+		t.Error("IsZero mismatch")
+	}
+}
+
+func TestU256_JSONRange(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	mid, _ := new(big.Int).SetString("750000000000000000000000000", 10)
+	for _, v := range []*big.Int{big.NewInt(0), mid, max} {
+		enc, err := json.Marshal(U256(*v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec U256
+		if err := json.Unmarshal(enc, &dec); err != nil || dec.ToInt().Cmp(v) != 0 {
+			t.Errorf("%v: have %v %v", v, dec.ToInt(), err)
+		}
+		var text U256
+		if err := text.UnmarshalText([]byte(hexutil.EncodeBig(v))); err != nil || text.ToInt().Cmp(v) != 0 {
+			t.Errorf("%v from hex text: have %v %v", v, text.ToInt(), err)
+		}
+	}
+	tooLarge := new(big.Int).Add(max, big.NewInt(1)).String()
+	for _, input := range []string{`-1`, `"-1"`, tooLarge, `"` + tooLarge + `"`, `"0x1` + strings.Repeat("0", 64) + `"`} {
+		var dec U256
+		if err := json.Unmarshal([]byte(input), &dec); err == nil {
+			t.Errorf("%s: have %v, expected error", input, dec.ToInt())
+		}
+	}
+	var text U256
+	if err := text.UnmarshalText([]byte("-5")); err == nil {
+		t.Error("text: expected error for a negative value")
+	}
+}
+
+func TestU256_MulU(t *testing.T) {
+	v, a := NewU256(6), NewU256(7)
+	if err := v.MulU(&a); err != nil || v.ToIntRef().Uint64() != 42 {
+		t.Fatalf("have %v %v, want 42", v.ToIntRef(), err)
+	}
+	max := U256(*new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)))
+	one, two := NewU256(1), NewU256(2)
+	if err := max.MulU(&one); err != nil || max.ToIntRef().BitLen() != 256 {
+		t.Fatalf("max * 1: have %v %v", max.ToIntRef(), err)
+	}
+	before := max.Clone()
+	if err := max.MulU(&two); err == nil {
+		t.Fatal("expected overflow error")
+	}
+	if max.Cmp(&before) != 0 {
+		t.Errorf("value changed by failed multiplication: %v", max.ToIntRef())
+	}
+}
+
+func TestU256_DivU(t *testing.T) {
+	v, a := NewU256(42), NewU256(7)
+	if err := v.DivU(&a); err != nil || v.ToIntRef().Uint64() != 6 {
+		t.Fatalf("exact: have %v %v, want 6", v.ToIntRef(), err)
+	}
+	v, a = NewU256(41), NewU256(7)
+	if err := v.DivU(&a); err != nil || v.ToIntRef().Uint64() != 5 {
+		t.Fatalf("truncating: have %v %v, want 5", v.ToIntRef(), err)
+	}
+	zero := NewU256(0)
+	if err := v.DivU(&zero); err == nil {
+		t.Fatal("expected division by zero error")
+	}
+	if v.ToIntRef().Uint64() != 5 {
+		t.Errorf("value changed by failed division: %v", v.ToIntRef())
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"regexp"
 	"runtime"
 	"strings"
 
@@ -58,12 +59,74 @@ func (self Uint256s) Swap(i, j int) {
 	self[i], self[j] = self[j], self[i]
 }
 
-func CurrencyToUint256(str string) (ret c_type.Uint256) {
+// CurrencyToUint256 returns the id of a currency name. Names follow the rules
+// the VM applies to issued tokens: an upper case letter followed by at most 31
+// upper case letters, digits or underscores.
+func CurrencyToUint256(str string) (ret c_type.Uint256, err error) {
+	if err = checkCurrency(str); err != nil {
+		return
+	}
+	copy(ret[:], CurrencyToBytes(str))
+	return
+}
+
+// SeroCurrency is the id of the SERO currency.
+var SeroCurrency = mustCurrencyToUint256("SERO")
+
+func mustCurrencyToUint256(str string) c_type.Uint256 {
+	ret, err := CurrencyToUint256(str)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// CurrencyToUint256Unchecked returns the id of a currency name without
+// validating it. Lower case letters are upper cased and names longer than 32
+// bytes are cut.
+//
+// Deprecated: use CurrencyToUint256, or SeroCurrency for SERO.
+func CurrencyToUint256Unchecked(str string) (ret c_type.Uint256) {
 	bs := CurrencyToBytes(str)
 	copy(ret[:], bs)
 	return
 }
 
+// NewCurrency returns the id of a currency name, checking that the name is
+// stored unchanged, i.e. Uint256ToCurrency gives back exactly s. It is the
+// same as CurrencyToUint256.
+func NewCurrency(s string) (c_type.Uint256, error) {
+	return CurrencyToUint256(s)
+}
+
+// DecodeCurrency returns the currency name of an id made by
+// CurrencyToUint256, checking that it is a valid name left padded with zero
+// bytes.
+func DecodeCurrency(u c_type.Uint256) (string, error) {
+	start := 0
+	for start < len(u) && u[start] == 0 {
+		start++
+	}
+	name := string(u[start:])
+	if err := checkCurrency(name); err != nil {
+		return "", fmt.Errorf("invalid currency id %x: %v", u[:], err)
+	}
+	return name, nil
+}
+
+var currencyPattern = regexp.MustCompile("^[A-Z][A-Z0-9_]*$")
+
+// checkCurrency checks that s is a valid currency name.
+func checkCurrency(s string) error {
+	if len(s) == 0 || len(s) > len(c_type.Uint256{}) {
+		return fmt.Errorf("currency %q: length must be between 1 and %d", s, len(c_type.Uint256{}))
+	}
+	if !currencyPattern.MatchString(s) {
+		return fmt.Errorf("currency %q: must be an upper case letter followed by upper case letters, digits or underscores", s)
+	}
+	return nil
+}
+
 func Uint256ToCurrency(u *c_type.Uint256) (ret string) {
 	return BytesToCurrency(u[:])
 }
@@ -47,3 +47,97 @@ func TestDeepCopy(t *testing.T) {
 
 	DeepCopy(&ct, &ct)
 }
+
+func TestNewCurrency(t *testing.T) {
+	for _, s := range []string{"SERO", "A", "SUSD_1", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"} {
+		c, err := NewCurrency(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if c != CurrencyToUint256Unchecked(s) || Uint256ToCurrency(&c) != s {
+			t.Errorf("%q: does not round-trip", s)
+		}
+	}
+	for _, s := range []string{"", "sero", "SE\x00RO", "SÉRO", "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456"} {
+		if _, err := NewCurrency(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestCurrencyToUint256(t *testing.T) {
+	for _, s := range []string{"SERO", "SERO_TICKET", "A1", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"} {
+		c, err := CurrencyToUint256(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if c != CurrencyToUint256Unchecked(s) {
+			t.Errorf("%q: have %x, want %x", s, c, CurrencyToUint256Unchecked(s))
+		}
+	}
+	for _, s := range []string{
+		"",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456", // 33 bytes
+		"SE RO",
+		" SERO",
+		"sero",
+		"1SERO",
+		"SERO-1",
+	} {
+		if c, err := CurrencyToUint256(s); err == nil {
+			t.Errorf("%q: have %x, expected error", s, c)
+		}
+		if _, err := NewAsset(s, NewU256(1)); err == nil {
+			t.Errorf("%q: expected error for an asset", s)
+		}
+	}
+	if Uint256ToCurrency(&SeroCurrency) != "SERO" {
+		t.Errorf("SeroCurrency: have %q", Uint256ToCurrency(&SeroCurrency))
+	}
+}
+
+func TestTokenCostMap(t *testing.T) {
+	seroCy := CurrencyToUint256Unchecked("SERO")
+	cy := CurrencyToUint256Unchecked("d")
+	cost := TokenCostMap{}
+	cost.Add(seroCy, NewU256(24))
+	cost.Add(seroCy, NewU256(12))
+	cost.Add(cy, NewU256(48))
+
+	total := func(cy c_type.Uint256) uint64 {
+		v := cost.Total(cy)
+		return v.ToIntRef().Uint64()
+	}
+	if total(seroCy) != 36 || total(cy) != 48 {
+		t.Fatalf("have SERO %d, d %d, want 36 and 48", total(seroCy), total(cy))
+	}
+	if err := cost.Sub(cy, NewU256(48)); err != nil || total(cy) != 0 {
+		t.Fatalf("have d %d %v, want 0", total(cy), err)
+	}
+	if err := cost.Sub(seroCy, NewU256(37)); err == nil || total(seroCy) != 36 {
+		t.Fatalf("underflow: have SERO %d %v, want an error and 36", total(seroCy), err)
+	}
+	if err := cost.Sub(CurrencyToUint256Unchecked("X"), NewU256(1)); err == nil {
+		t.Fatal("expected underflow error for an absent currency")
+	}
+	if total(CurrencyToUint256Unchecked("X")) != 0 {
+		t.Errorf("absent currency: have %d, want 0", total(CurrencyToUint256Unchecked("X")))
+	}
+}
+
+func TestDecodeCurrency(t *testing.T) {
+	for s, want := range map[string]string{"SERO": "SERO", "d": "D", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345": "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"} {
+		name, err := DecodeCurrency(CurrencyToUint256Unchecked(s))
+		if err != nil || name != want {
+			t.Errorf("%q: have %q %v, want %q", s, name, err, want)
+		}
+	}
+	var zero, embedded, lower c_type.Uint256
+	embedded[29], embedded[31] = 'A', 'B'
+	copy(lower[28:], "sero")
+	for _, u := range []c_type.Uint256{zero, embedded, lower} {
+		if name, err := DecodeCurrency(u); err == nil {
+			t.Errorf("%x: have %q, expected error", u, name)
+		}
+	}
+}
@@ -1195,7 +1195,7 @@ func (self *Exchange) getMergeUtxos(from *c_type.Uint512, currency string, zcoun
 	if icount <= 0 {
 		icount = 1000
 	}
-	ck := assets.NewCKState(true, &assets.Token{utils.CurrencyToUint256("SERO"), utils.U256(*default_fee_value)})
+	ck := assets.NewCKState(true, &assets.Token{utils.SeroCurrency, utils.U256(*default_fee_value)})
 	prefix := utxoPkKey(*from, common.LeftPadBytes([]byte(currency), 32), nil)
 	iterator := self.db.NewIteratorWithPrefix(prefix)
 	outxos := UtxoList{}
@@ -1280,7 +1280,7 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 	var Currency c_type.Uint256
 	copy(Currency[:], bytes[:])
 
-	ck := assets.NewCKState(false, &assets.Token{utils.CurrencyToUint256("SERO"), utils.U256(*default_fee_value)})
+	ck := assets.NewCKState(false, &assets.Token{utils.SeroCurrency, utils.U256(*default_fee_value)})
 
 	for _, utxo := range mu.list {
 		ck.AddIn(&utxo.Asset)
@@ -1314,7 +1314,7 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 
 	bparam := prepare.BeforeTxParam{
 		assets.Token{
-			utils.CurrencyToUint256("SERO"),
+			utils.SeroCurrency,
 			utils.U256(*default_fee_value),
 		},
 		*big.NewInt(1000000000),
@@ -1356,7 +1356,7 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 		var Currency c_type.Uint256
 		copy(Currency[:], bytes[:])
 
-		ck := assets.NewCKState(false, &assets.Token{utils.CurrencyToUint256("SERO"), utils.U256(*default_fee_value)})
+		ck := assets.NewCKState(false, &assets.Token{utils.SeroCurrency, utils.U256(*default_fee_value)})
 
 		for _, utxo := range mu.list {
 			ck.AddIn(&utxo.Asset)
@@ -1390,7 +1390,7 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 
 		bparam := prepare.BeforeTxParam{
 			assets.Token{
-				utils.CurrencyToUint256("SERO"),
+				utils.SeroCurrency,
 				utils.U256(*default_fee_value),
 			},
 			*big.NewInt(1000000000),
@@ -106,7 +106,7 @@ func (self *SSI) GenTxParam(param *PreTxParam) (p txtool.GTxParam, e error) {
 	p.Gas = param.Gas
 	p.GasPrice = big.NewInt(0).SetUint64(param.GasPrice)
 	p.Fee = assets.Token{
-		utils.CurrencyToUint256("SERO"),
+		utils.SeroCurrency,
 		utils.U256(*new(big.Int).Mul(new(big.Int).SetUint64(param.Gas), new(big.Int).SetUint64(param.GasPrice))),
 	}
 	p.From = param.From
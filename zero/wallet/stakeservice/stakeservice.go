@@ -190,7 +190,7 @@ func (self *StakeService) SharesByPkr(pkr c_type.PKr) (shares []*stake.Share) {
 	return
 }
 
-func (self *StakeService) GetBlockRecords(blockNumber uint64) (shares []*stake.Share, pools []*stake.StakePool) {
+func (self *StakeService) GetBlockRecords(blockNumber uint64) (shares []*stake.Share, pools []*stake.StakePool, err error) {
 	header := self.bc.GetHeaderByNumber(blockNumber)
 	return stake.GetBlockRecords(self.bc.GetDB(), header.Hash(), blockNumber)
 }
@@ -262,7 +262,12 @@ func (self *StakeService) stakeIndex() {
 	pkrStakeInfoCache := map[c_type.PKr]*SharesInfo{}
 	pkStakeInfoCache := map[c_type.Uint512]*SharesInfo{}
 	for blocNumber+seroparam.DefaultConfirmedBlock() <= header.Number.Uint64() {
-		shares, pools := self.GetBlockRecords(blocNumber)
+		shares, pools, err := self.GetBlockRecords(blocNumber)
+		if err != nil {
+			// index up to here, the next run starts again at this block
+			log.Error("StakeIndex", "blockNumber", blocNumber, "err", err)
+			break
+		}
 		for _, share := range shares {
 			// batch.Put(sharekey(share.Id()), share.State())
 			// batch.Put(pkrShareKey(share.PKr, share.Id()), share.State())
@@ -408,7 +413,10 @@ func (self *StakeService) updateSharesInfo(share *stake.Share, sharesCache map[c
 	if share.LastPayTime == blocNumber {
 		if oldShare != nil && oldShare.LastPayTime != 0 {
 			header := self.bc.GetBlockByNumber(oldShare.LastPayTime)
-			snapshot := stake.GetShareByBlockNumber(self.bc.GetDB(), id, header.Hash(), header.NumberU64())
+			snapshot, err := stake.GetShareByBlockNumber(self.bc.GetDB(), id, header.Hash(), header.NumberU64())
+			if err != nil {
+				log.Error("Failed to get share snapshot", "id", id.Hex(), "number", oldShare.LastPayTime, "err", err)
+			}
 			if snapshot != nil {
 				mul := new(big.Int).Mul(big.NewInt(int64(
 					(snapshot.Num+snapshot.WillVoteNum)-(share.Num+share.WillVoteNum))),